@@ -0,0 +1,25 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+const defaultOccupancyReconcileInterval = 5 * time.Minute
+
+// OccupancyReconcileInterval is how often the cached block occupancy bitmaps
+// are rebuilt from the database, read from OCCUPANCY_RECONCILE_INTERVAL.
+func OccupancyReconcileInterval() time.Duration {
+	value := os.Getenv("OCCUPANCY_RECONCILE_INTERVAL")
+	if value == "" {
+		return defaultOccupancyReconcileInterval
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Printf("⚠️ Invalid OCCUPANCY_RECONCILE_INTERVAL %q, using %s", value, defaultOccupancyReconcileInterval)
+		return defaultOccupancyReconcileInterval
+	}
+	return interval
+}
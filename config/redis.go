@@ -13,6 +13,11 @@ import (
 var RedisClient *redis.Client
 var Ctx = context.Background()
 
+// ConnectRedis connects the cache. Redis is optional: when it cannot be
+// reached RedisClient stays nil and the services run without caching. The
+// connection is only tried here, so Redis must be up when the service starts;
+// one that comes up later is not used, nor is the occupancy reconciler run,
+// until a restart.
 func ConnectRedis() {
 	redisHost := os.Getenv("REDIS_HOST")
 	if redisHost == "" {
@@ -45,7 +50,10 @@ func ConnectRedis() {
 	//test connection
 	_, err := RedisClient.Ping(Ctx).Result()
 	if err != nil {
-		log.Fatalf("Gagal connection ke redis: %v", err)
+		log.Printf("⚠️ Redis unavailable, running without cache until restart: %v", err)
+		RedisClient.Close()
+		RedisClient = nil
+		return
 	}
 	log.Println("Connected to redis")
 }
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+const defaultSuggestionReservationTTL = 5 * time.Minute
+
+// SuggestionReservationTTL is how long a suggested position stays reserved for
+// its container, read from SUGGESTION_RESERVATION_TTL (e.g. "90s", "10m").
+func SuggestionReservationTTL() time.Duration {
+	value := os.Getenv("SUGGESTION_RESERVATION_TTL")
+	if value == "" {
+		return defaultSuggestionReservationTTL
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		log.Printf("⚠️ Invalid SUGGESTION_RESERVATION_TTL %q, using %s", value, defaultSuggestionReservationTTL)
+		return defaultSuggestionReservationTTL
+	}
+	return ttl
+}
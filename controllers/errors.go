@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend_yard_planning_system/services"
+)
+
+// respondError maps a ServiceError to its HTTP status. Any other error is a
+// failure on our side, such as a database error, and answers 500.
+func respondError(ctx *fiber.Ctx, err error) error {
+	var svcErr *services.ServiceError
+	if !errors.As(err, &svcErr) {
+		log.Printf("❌ Internal error on %s %s: %v", ctx.Method(), ctx.Path(), err)
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	status := fiber.StatusBadRequest
+	switch svcErr.Kind {
+	case services.KindNotFound:
+		status = fiber.StatusNotFound
+	case services.KindConflict:
+		status = fiber.StatusConflict
+	case services.KindRuleViolation:
+		status = fiber.StatusUnprocessableEntity
+	}
+
+	body := fiber.Map{
+		"error": svcErr.Message,
+		"code":  svcErr.Code,
+	}
+	if svcErr.Details != nil {
+		body["details"] = svcErr.Details
+	}
+
+	return ctx.Status(status).JSON(body)
+}
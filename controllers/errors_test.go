@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend_yard_planning_system/services"
+)
+
+func TestRespondErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"invalid", &services.ServiceError{Kind: services.KindInvalid, Code: "bad"}, fiber.StatusBadRequest},
+		{"not found", &services.ServiceError{Kind: services.KindNotFound, Code: "yard_not_found"}, fiber.StatusNotFound},
+		{"conflict", &services.ServiceError{Kind: services.KindConflict, Code: "taken"}, fiber.StatusConflict},
+		{"rule violation", &services.ServiceError{Kind: services.KindRuleViolation, Code: "full"}, fiber.StatusUnprocessableEntity},
+		{"internal", errors.New("connection refused"), fiber.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(ctx *fiber.Ctx) error { return respondError(ctx, tt.err) })
+
+			response, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			if err != nil {
+				t.Fatalf("app.Test: %v", err)
+			}
+			if response.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d", response.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,8 @@
 package controllers
 
 import (
-	"backend_yard_planning_system/database"
-	"backend_yard_planning_system/models"
+	"context"
+	"fmt"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -16,16 +16,25 @@ type YardController struct {
 	validate    *validator.Validate
 }
 
-func NewYardController() *YardController {
+func NewYardController(yardService *services.YardService) *YardController {
 	validate := validator.New()
 	dto.RegisterCustomValidations(validate)
 
 	return &YardController{
-		yardService: services.NewYardService(),
+		yardService: yardService,
 		validate:    validate,
 	}
 }
 
+// validationContext relaxes the ISO 6346 container number check for yards
+// that allow legacy numbers.
+func (c *YardController) validationContext(ctx *fiber.Ctx, yardName string) context.Context {
+	if c.yardService.RelaxesContainerNumbers(yardName) {
+		return dto.WithRelaxedContainerNumbers(ctx.UserContext())
+	}
+	return ctx.UserContext()
+}
+
 func (c *YardController) GetSuggestion(ctx *fiber.Ctx) error {
 	var req dto.SuggestionRequest
 
@@ -34,20 +43,32 @@ func (c *YardController) GetSuggestion(ctx *fiber.Ctx) error {
 			"error": "Invalid request body: " + err.Error(),
 		})
 	}
+	req.ContainerNumber = dto.NormalizeContainerNumber(req.ContainerNumber)
 
-	if err := c.validate.Struct(req); err != nil {
+	if err := req.ApplySizeType(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := c.validate.StructCtx(c.validationContext(ctx, req.Yard), req); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": dto.GetValidationError(err),
 		})
 	}
 
-	response, err := c.yardService.GetSuggestion(req)
-	if err != nil {
+	limit := ctx.QueryInt("limit", 1)
+	if limit < 1 || limit > dto.MaxSuggestionLimit {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
+			"error": fmt.Sprintf("limit must be between 1 and %d", dto.MaxSuggestionLimit),
 		})
 	}
 
+	response, err := c.yardService.GetSuggestion(req, limit)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+
 	return ctx.JSON(response)
 }
 
@@ -59,32 +80,38 @@ func (c *YardController) PlaceContainer(ctx *fiber.Ctx) error {
 			"error": "Invalid request body: " + err.Error(),
 		})
 	}
+	req.ContainerNumber = dto.NormalizeContainerNumber(req.ContainerNumber)
 
-	if err := c.validate.Struct(req); err != nil {
+	if err := req.ApplySizeType(); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": dto.GetValidationError(err),
+			"error": err.Error(),
 		})
 	}
 
-	if err := c.yardService.PlaceContainer(req); err != nil {
+	if err := c.validate.StructCtx(c.validationContext(ctx, req.Yard), req); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
+			"error": dto.GetValidationError(err),
 		})
 	}
 
+	if err := c.yardService.PlaceContainer(req); err != nil {
+		return respondError(ctx, err)
+	}
+
 	return ctx.JSON(dto.MessageResponse{
 		Message: "Success",
 	})
 }
 
-func (c *YardController) PickupContainer(ctx *fiber.Ctx) error {
-	var req dto.PickupRequest
+func (c *YardController) MoveContainer(ctx *fiber.Ctx) error {
+	var req dto.MoveRequest
 
 	if err := ctx.BodyParser(&req); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid request body: " + err.Error(),
 		})
 	}
+	req.ContainerNumber = dto.NormalizeContainerNumber(req.ContainerNumber)
 
 	if err := c.validate.Struct(req); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -92,28 +119,42 @@ func (c *YardController) PickupContainer(ctx *fiber.Ctx) error {
 		})
 	}
 
-	if err := c.yardService.PickupContainer(req); err != nil {
+	move, err := c.yardService.MoveContainer(req)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+
+	return ctx.JSON(move)
+}
+
+func (c *YardController) PickupContainer(ctx *fiber.Ctx) error {
+	var req dto.PickupRequest
+
+	if err := ctx.BodyParser(&req); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
+			"error": "Invalid request body: " + err.Error(),
 		})
 	}
+	req.ContainerNumber = dto.NormalizeContainerNumber(req.ContainerNumber)
 
-	return ctx.JSON(dto.MessageResponse{
-		Message: "Success",
-	})
+	if err := c.validate.Struct(req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": dto.GetValidationError(err),
+		})
+	}
+
+	response, err := c.yardService.PickupContainer(req)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+
+	return ctx.JSON(response)
 }
 
 func (c *YardController) GetYardPlans(ctx *fiber.Ctx) error {
 	yardName := ctx.Query("yard", "YRD1")
 
-	var yardPlans []models.YardPlan
-	err := database.DB.
-		Joins("JOIN blocks ON blocks.id = yard_plans.block_id").
-		Joins("JOIN yards ON yards.id = blocks.yard_id").
-		Where("yards.name = ?", yardName).
-		Preload("Block").
-		Find(&yardPlans).Error
-
+	yardPlans, err := c.yardService.GetYardPlans(yardName)
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -125,3 +166,81 @@ func (c *YardController) GetYardPlans(ctx *fiber.Ctx) error {
 		"plans": yardPlans,
 	})
 }
+
+func (c *YardController) GetWeightViolations(ctx *fiber.Ctx) error {
+	yardName := ctx.Params("yard")
+
+	violations, err := c.yardService.GetWeightViolations(yardName)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"yard":       yardName,
+		"violations": violations,
+	})
+}
+
+func (c *YardController) ListReservations(ctx *fiber.Ctx) error {
+	yardName := ctx.Params("yard")
+	reservations, err := c.yardService.ListReservations(yardName)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"yard":         yardName,
+		"reservations": reservations,
+	})
+}
+
+func (c *YardController) CancelReservation(ctx *fiber.Ctx) error {
+	if err := c.yardService.CancelReservation(ctx.Params("yard"), dto.NormalizeContainerNumber(ctx.Params("container"))); err != nil {
+		return respondError(ctx, err)
+	}
+
+	return ctx.JSON(dto.MessageResponse{
+		Message: "Success",
+	})
+}
+
+func (c *YardController) GetContainerHistory(ctx *fiber.Ctx) error {
+	history, err := c.yardService.GetContainerHistory(dto.NormalizeContainerNumber(ctx.Params("number")))
+	if err != nil {
+		return respondError(ctx, err)
+	}
+
+	return ctx.JSON(history)
+}
+
+func (c *YardController) GetContainer(ctx *fiber.Ctx) error {
+	container, err := c.yardService.GetContainer(dto.NormalizeContainerNumber(ctx.Params("number")))
+	if err != nil {
+		return respondError(ctx, err)
+	}
+
+	return ctx.JSON(container)
+}
+
+func (c *YardController) SearchContainers(ctx *fiber.Ctx) error {
+	var query dto.ContainerSearchQuery
+
+	if err := ctx.QueryParser(&query); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid query: " + err.Error(),
+		})
+	}
+
+	if err := c.validate.Struct(query); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": dto.GetValidationError(err),
+		})
+	}
+
+	response, err := c.yardService.SearchContainers(query)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+
+	return ctx.JSON(response)
+}
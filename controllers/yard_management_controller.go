@@ -0,0 +1,278 @@
+package controllers
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/services"
+)
+
+type YardManagementController struct {
+	managementService *services.YardManagementService
+	validate          *validator.Validate
+}
+
+func NewYardManagementController(managementService *services.YardManagementService) *YardManagementController {
+	validate := validator.New()
+	dto.RegisterCustomValidations(validate)
+
+	return &YardManagementController{
+		managementService: managementService,
+		validate:          validate,
+	}
+}
+
+// parseBody decodes and validates the request body, writing a 400 response on
+// failure. The returned bool reports whether the handler should continue.
+func (c *YardManagementController) parseBody(ctx *fiber.Ctx, req interface{}) (bool, error) {
+	if err := ctx.BodyParser(req); err != nil {
+		return false, ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	if err := c.validate.Struct(req); err != nil {
+		return false, ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": dto.GetValidationError(err),
+		})
+	}
+
+	return true, nil
+}
+
+func parsePlanID(ctx *fiber.Ctx) (uint, bool, error) {
+	id, err := ctx.ParamsInt("id")
+	if err != nil || id < 1 {
+		return 0, false, ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "plan id must be a positive integer",
+		})
+	}
+	return uint(id), true, nil
+}
+
+// Yards
+
+func (c *YardManagementController) ListYards(ctx *fiber.Ctx) error {
+	yards, err := c.managementService.ListYards()
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{"yards": yards})
+}
+
+func (c *YardManagementController) GetYard(ctx *fiber.Ctx) error {
+	yard, err := c.managementService.GetYard(ctx.Params("yard"))
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(yard)
+}
+
+func (c *YardManagementController) CreateYard(ctx *fiber.Ctx) error {
+	var req dto.YardRequest
+	if ok, err := c.parseBody(ctx, &req); !ok {
+		return err
+	}
+
+	yard, err := c.managementService.CreateYard(req)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(yard)
+}
+
+func (c *YardManagementController) UpdateYard(ctx *fiber.Ctx) error {
+	var req dto.YardRequest
+	if ok, err := c.parseBody(ctx, &req); !ok {
+		return err
+	}
+
+	yard, err := c.managementService.UpdateYard(ctx.Params("yard"), req)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(yard)
+}
+
+func (c *YardManagementController) DeleteYard(ctx *fiber.Ctx) error {
+	if err := c.managementService.DeleteYard(ctx.Params("yard")); err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(dto.MessageResponse{Message: "Success"})
+}
+
+func (c *YardManagementController) GetWeightClasses(ctx *fiber.Ctx) error {
+	yardName := ctx.Params("yard")
+	classes, err := c.managementService.GetWeightClasses(yardName)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{
+		"yard":    yardName,
+		"classes": classes,
+	})
+}
+
+func (c *YardManagementController) SetWeightClasses(ctx *fiber.Ctx) error {
+	var req dto.WeightClassesRequest
+	if ok, err := c.parseBody(ctx, &req); !ok {
+		return err
+	}
+
+	yardName := ctx.Params("yard")
+	classes, err := c.managementService.SetWeightClasses(yardName, req)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{
+		"yard":    yardName,
+		"classes": classes,
+	})
+}
+
+// Blocks
+
+func (c *YardManagementController) ListBlocks(ctx *fiber.Ctx) error {
+	yardName := ctx.Params("yard")
+	blocks, err := c.managementService.ListBlocks(yardName)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{
+		"yard":   yardName,
+		"blocks": blocks,
+	})
+}
+
+func (c *YardManagementController) GetBlock(ctx *fiber.Ctx) error {
+	block, err := c.managementService.GetBlock(ctx.Params("yard"), ctx.Params("block"))
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(block)
+}
+
+func (c *YardManagementController) CreateBlock(ctx *fiber.Ctx) error {
+	var req dto.BlockRequest
+	if ok, err := c.parseBody(ctx, &req); !ok {
+		return err
+	}
+
+	block, err := c.managementService.CreateBlock(ctx.Params("yard"), req)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(block)
+}
+
+func (c *YardManagementController) UpdateBlock(ctx *fiber.Ctx) error {
+	var req dto.BlockRequest
+	if ok, err := c.parseBody(ctx, &req); !ok {
+		return err
+	}
+
+	block, err := c.managementService.UpdateBlock(ctx.Params("yard"), ctx.Params("block"), req)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(block)
+}
+
+func (c *YardManagementController) DeleteBlock(ctx *fiber.Ctx) error {
+	if err := c.managementService.DeleteBlock(ctx.Params("yard"), ctx.Params("block")); err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(dto.MessageResponse{Message: "Success"})
+}
+
+func (c *YardManagementController) GetReeferPlugs(ctx *fiber.Ctx) error {
+	utilization, err := c.managementService.GetReeferUtilization(ctx.Params("yard"), ctx.Params("block"))
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(utilization)
+}
+
+func (c *YardManagementController) SetReeferPlugs(ctx *fiber.Ctx) error {
+	var req dto.ReeferPlugsRequest
+	if ok, err := c.parseBody(ctx, &req); !ok {
+		return err
+	}
+
+	utilization, err := c.managementService.SetReeferPlugs(ctx.Params("yard"), ctx.Params("block"), req)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(utilization)
+}
+
+// Yard plans
+
+func (c *YardManagementController) ListPlans(ctx *fiber.Ctx) error {
+	yardName := ctx.Params("yard")
+	plans, err := c.managementService.ListPlans(yardName)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{
+		"yard":  yardName,
+		"plans": plans,
+	})
+}
+
+func (c *YardManagementController) GetPlan(ctx *fiber.Ctx) error {
+	planID, ok, err := parsePlanID(ctx)
+	if !ok {
+		return err
+	}
+
+	plan, err := c.managementService.GetPlan(ctx.Params("yard"), planID)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(plan)
+}
+
+func (c *YardManagementController) CreatePlan(ctx *fiber.Ctx) error {
+	var req dto.YardPlanRequest
+	if ok, err := c.parseBody(ctx, &req); !ok {
+		return err
+	}
+
+	plan, err := c.managementService.CreatePlan(ctx.Params("yard"), req)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(plan)
+}
+
+func (c *YardManagementController) UpdatePlan(ctx *fiber.Ctx) error {
+	planID, ok, err := parsePlanID(ctx)
+	if !ok {
+		return err
+	}
+
+	var req dto.YardPlanRequest
+	if ok, err := c.parseBody(ctx, &req); !ok {
+		return err
+	}
+
+	plan, err := c.managementService.UpdatePlan(ctx.Params("yard"), planID, req)
+	if err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(plan)
+}
+
+func (c *YardManagementController) DeletePlan(ctx *fiber.Ctx) error {
+	planID, ok, err := parsePlanID(ctx)
+	if !ok {
+		return err
+	}
+
+	if err := c.managementService.DeletePlan(ctx.Params("yard"), planID); err != nil {
+		return respondError(ctx, err)
+	}
+	return ctx.JSON(dto.MessageResponse{Message: "Success"})
+}
@@ -1,59 +1,144 @@
 package database
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strings"
+	"sync/atomic"
 
 	"backend_yard_planning_system/models"
 
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 var DB *gorm.DB
 
+// memoryDatabases numbers the in-memory SQLite databases of the process.
+var memoryDatabases atomic.Int64
+
+// ConnectDB opens the database named by DB_DRIVER and DATABASE_URL, migrates
+// it and seeds an empty one.
 func ConnectDB() {
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		dsn = "host=localhost user=postgres password=postgres dbname=yard_planning_db port=5432 sslmode=disable TimeZone=Asia/Jakarta"
+	driver := os.Getenv("DB_DRIVER")
+	if driver == "" {
+		driver = DriverPostgres
 	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	})
-
+	db, err := Open(driver, os.Getenv("DATABASE_URL"))
 	if err != nil {
 		log.Fatal("Failed to connect to database: ", err)
 	}
 
-	log.Println("Connected to database")
+	log.Printf("Connected to database (%s)", driver)
+	DB = db
+
+	if err := Migrate(db); err != nil {
+		log.Fatal("Failed to migrate database: ", err)
+	}
+
+	log.Println("Database migrated successfully")
+
+	seedInitialData(db)
+}
+
+// Open connects to a database. For postgres dsn is a connection string; for
+// sqlite it is a file path or ":memory:". An empty dsn picks the local
+// default of the driver.
+func Open(driver, dsn string) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch driver {
+	case DriverPostgres:
+		if dsn == "" {
+			dsn = "host=localhost user=postgres password=postgres dbname=yard_planning_db port=5432 sslmode=disable TimeZone=Asia/Jakarta"
+		}
+		dialector = postgres.Open(dsn)
+	case DriverSQLite:
+		dialector = sqlite.Open(sqliteDSN(dsn))
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q, expected %s or %s", driver, DriverPostgres, DriverSQLite)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Info),
+		TranslateError: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if driver == DriverSQLite {
+		// SQLite allows one writer at a time. A single connection queues the
+		// transactions instead of failing them with "database is locked", and
+		// keeps every query on the same in-memory database.
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+
+	return db, nil
+}
+
+// sqliteDSN turns a file path or ":memory:" into a SQLite DSN that enforces
+// foreign keys like Postgres does. Every ":memory:" gets a database of its own.
+func sqliteDSN(path string) string {
+	switch path {
+	case "":
+		path = "yard_planning.db"
+	case ":memory:":
+		path = fmt.Sprintf("file:memdb%d?mode=memory&cache=shared", memoryDatabases.Add(1))
+	}
 
-	// Auto migrate
-	err = db.AutoMigrate(
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + "_foreign_keys=1"
+}
+
+// Migrate creates or updates the schema and backfills columns added since.
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(
 		&models.Yard{},
 		&models.Block{},
 		&models.YardPlan{},
+		&models.ReeferPlug{},
+		&models.WeightClass{},
 		&models.Container{},
+		&models.Suggestion{},
+		&models.ContainerEvent{},
 	)
-
 	if err != nil {
-		log.Fatal("Failed to migrate database: ", err)
+		return err
 	}
 
-	log.Println("Database migrated successfully")
-
-	seedInitialData()
+	// Containers placed before footprints were tracked default to a single slot.
+	if err := db.Model(&models.Container{}).
+		Where("container_size = ? AND slot_span = ?", 40, 1).
+		Update("slot_span", 2).Error; err != nil {
+		return fmt.Errorf("backfill container footprints: %w", err)
+	}
+	return nil
 }
 
-func seedInitialData() {
+func seedInitialData(db *gorm.DB) {
 	var yardCount int64
-	DB.Model(&models.Yard{}).Count(&yardCount)
+	db.Model(&models.Yard{}).Count(&yardCount)
 
 	if yardCount == 0 {
 
 		yard := models.Yard{Name: "YRD1"}
-		DB.Create(&yard)
+		db.Create(&yard)
 
 		block := models.Block{
 			YardID:  yard.ID,
@@ -62,7 +147,7 @@ func seedInitialData() {
 			MaxRow:  5,
 			MaxTier: 4,
 		}
-		DB.Create(&block)
+		db.Create(&block)
 
 		plans := []models.YardPlan{
 			{
@@ -102,7 +187,7 @@ func seedInitialData() {
 		}
 
 		for _, plan := range plans {
-			DB.Create(&plan)
+			db.Create(&plan)
 		}
 
 		log.Println("Initial data seeded successfully")
@@ -0,0 +1,53 @@
+package dto
+
+import "time"
+
+const (
+	ContainerStatusPlaced   = "placed"
+	ContainerStatusPickedUp = "picked_up"
+
+	DefaultContainerSearchLimit = 50
+)
+
+// ContainerResponse is a container with its current, or for a picked up
+// container its last, position.
+type ContainerResponse struct {
+	ContainerNumber   string     `json:"container_number"`
+	Yard              string     `json:"yard"`
+	Block             string     `json:"block"`
+	Slot              int        `json:"slot"`
+	SlotSpan          int        `json:"slot_span"`
+	Row               int        `json:"row"`
+	Tier              int        `json:"tier"`
+	ContainerSize     int        `json:"container_size"`
+	ContainerHeight   float64    `json:"container_height"`
+	ContainerType     string     `json:"container_type"`
+	SizeType          string     `json:"size_type,omitempty"`
+	OutOfGauge        bool       `json:"out_of_gauge"`
+	GrossWeight       float64    `json:"gross_weight"`
+	ExpectedDeparture *time.Time `json:"expected_departure,omitempty"`
+	VesselVoyage      string     `json:"vessel_voyage,omitempty"`
+	Status            string     `json:"status"`
+	PlacedAt          time.Time  `json:"placed_at"`
+	PickedUpAt        *time.Time `json:"picked_up_at,omitempty"`
+}
+
+// ContainerSearchQuery filters GET /api/containers. Times are RFC 3339, with
+// optional fractional seconds, and are parsed by the service; Cursor is the
+// next_cursor of the previous page.
+type ContainerSearchQuery struct {
+	Yard          string `query:"yard"`
+	Block         string `query:"block"`
+	ContainerType string `query:"type" validate:"omitempty,oneof=DRY REEFER OPEN_TOP FLAT_RACK TANK"`
+	ContainerSize int    `query:"size" validate:"omitempty,oneof=10 20 40 45"`
+	Status        string `query:"status" validate:"omitempty,oneof=placed picked_up"`
+	PlacedBefore  string `query:"placed_before"`
+	PlacedAfter   string `query:"placed_after"`
+	Cursor        string `query:"cursor"`
+	Limit         int    `query:"limit" validate:"omitempty,min=1,max=200"`
+}
+
+type ContainerSearchResponse struct {
+	Containers []ContainerResponse `json:"containers"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
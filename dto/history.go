@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+// ContainerEvent is one entry of a container's history. From is set for moves
+// and pickups, To for suggestions, placements and moves.
+type ContainerEvent struct {
+	Type       string    `json:"type"`
+	Yard       string    `json:"yard"`
+	From       *Position `json:"from,omitempty"`
+	To         *Position `json:"to,omitempty"`
+	User       string    `json:"user,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+type ContainerHistoryResponse struct {
+	ContainerNumber string           `json:"container_number"`
+	Events          []ContainerEvent `json:"events"`
+}
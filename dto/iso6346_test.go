@@ -0,0 +1,83 @@
+package dto
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestCheckISO6346(t *testing.T) {
+	tests := []struct {
+		number  string
+		wantErr string // part of the error, "" when valid
+	}{
+		{"CSQU3054383", ""},
+		{"ABCJ1234563", ""},
+		{"ABCZ1234564", ""},
+		{"MSCU1000070", ""}, // check value 10 gives check digit 0
+		{"CSQU3054384", "check digit must be 3"},
+		{"MSCU1000071", "check digit must be 0"},
+		{"CSQA3054383", "category identifier"},
+		{"CSQU305438", "must be 11 characters"},
+		{"CSQU30543833", "must be 11 characters"},
+		{"C5QU3054383", "owner code"},
+		{"CSQU3O54383", "serial number"},
+		{"csqu3054383", "owner code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			err := CheckISO6346(tt.number)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CheckISO6346() = %v, want valid", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("CheckISO6346() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeContainerNumber(t *testing.T) {
+	for _, number := range []string{"csqu3054383", " CSQU 305438 3 ", "csqu\t305438\n3"} {
+		got := NormalizeContainerNumber(number)
+		if got != "CSQU3054383" {
+			t.Fatalf("NormalizeContainerNumber(%q) = %q, want CSQU3054383", number, got)
+		}
+		if err := CheckISO6346(got); err != nil {
+			t.Fatalf("CheckISO6346(%q) = %v", got, err)
+		}
+	}
+}
+
+func TestContainerNumberValidation(t *testing.T) {
+	validate := validator.New()
+	RegisterCustomValidations(validate)
+	request := func(number string) SuggestionRequest {
+		return SuggestionRequest{Yard: "YRD1", ContainerNumber: number, ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY"}
+	}
+	strict, relaxed := context.Background(), WithRelaxedContainerNumbers(context.Background())
+
+	if err := validate.StructCtx(strict, request("CSQU3054383")); err != nil {
+		t.Fatalf("valid number rejected: %v", err)
+	}
+	err := validate.StructCtx(strict, request("CSQU3054384"))
+	if err == nil {
+		t.Fatal("wrong check digit accepted")
+	}
+	if got, want := GetValidationError(err), "container_number check digit must be 3, got \"4\""; got != want {
+		t.Fatalf("GetValidationError() = %q, want %q", got, want)
+	}
+
+	// Yards that relax the check still require a number.
+	if err := validate.StructCtx(relaxed, request("TEST0001")); err != nil {
+		t.Fatalf("relaxed yard rejected a legacy number: %v", err)
+	}
+	if err := validate.StructCtx(relaxed, request("")); err == nil {
+		t.Fatal("relaxed yard accepted an empty number")
+	}
+}
@@ -0,0 +1,30 @@
+package dto
+
+type ReeferPlugRequest struct {
+	Slot  int `json:"slot" validate:"required,min=1"`
+	Row   int `json:"row" validate:"required,min=1"`
+	Tiers int `json:"tiers" validate:"required,min=1"`
+}
+
+// ReeferPlugsRequest replaces the full set of power points of a block.
+type ReeferPlugsRequest struct {
+	Plugs []ReeferPlugRequest `json:"plugs" validate:"dive"`
+}
+
+type ReeferPlugUsage struct {
+	Slot       int      `json:"slot"`
+	Row        int      `json:"row"`
+	Tiers      int      `json:"tiers"`
+	Used       int      `json:"used"`
+	Containers []string `json:"containers"`
+}
+
+type ReeferUtilizationResponse struct {
+	Yard        string            `json:"yard"`
+	Block       string            `json:"block"`
+	Plugs       int               `json:"plugs"`
+	Capacity    int               `json:"capacity"`
+	Used        int               `json:"used"`
+	Utilization float64           `json:"utilization"` // Used / Capacity, 0 when the block has no plugs
+	Positions   []ReeferPlugUsage `json:"positions"`
+}
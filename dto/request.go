@@ -2,41 +2,84 @@ package dto
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
 
 func RegisterCustomValidations(validate *validator.Validate) {
 	validate.RegisterValidation("container_height", validateContainerHeight)
+	validate.RegisterValidationCtx("iso6346", validateISO6346)
 }
 
 func validateContainerHeight(fl validator.FieldLevel) bool {
 	if height, ok := fl.Field().Interface().(float64); ok {
-		return height == 8.6 || height == 9.6
+		return height == 8.0 || height == 8.6 || height == 9.0 || height == 9.6
 	}
 	return false
 }
 
 type SuggestionRequest struct {
 	Yard            string  `json:"yard" validate:"required"`
-	ContainerNumber string  `json:"container_number" validate:"required"`
-	ContainerSize   int     `json:"container_size" validate:"required,oneof=20 40"`
+	ContainerNumber string  `json:"container_number" validate:"required,iso6346"`
+	ContainerSize   int     `json:"container_size" validate:"required,oneof=10 20 40 45"`
 	ContainerHeight float64 `json:"container_height" validate:"required,container_height"`
-	ContainerType   string  `json:"container_type" validate:"required,oneof=DRY REEFER OPEN_TOP"`
+	ContainerType   string  `json:"container_type" validate:"required,oneof=DRY REEFER OPEN_TOP FLAT_RACK TANK"`
+	// SizeType is an ISO 6346 size-type code (e.g. 22G1) that may be sent
+	// instead of the three fields above.
+	SizeType    string  `json:"size_type"`
+	OutOfGauge  bool    `json:"out_of_gauge"`
+	GrossWeight float64 `json:"gross_weight" validate:"omitempty,gt=0"`
+	// Optional departure information used by departure-aware strategies.
+	ExpectedDeparture *time.Time `json:"expected_departure"`
+	VesselVoyage      string     `json:"vessel_voyage"`
+	User              string     `json:"user"`
 }
 
+// PlacementRequest places a container at an explicit position. Its attributes
+// may come from an earlier suggestion or a size-type code. SupervisorOverride
+// skips the reservation and yard plan checks.
 type PlacementRequest struct {
-	Yard            string `json:"yard" validate:"required"`
-	ContainerNumber string `json:"container_number" validate:"required"`
-	Block           string `json:"block" validate:"required"`
-	Slot            int    `json:"slot" validate:"required,min=1"`
-	Row             int    `json:"row" validate:"required,min=1"`
-	Tier            int    `json:"tier" validate:"required,min=1"`
+	Yard               string     `json:"yard" validate:"required"`
+	ContainerNumber    string     `json:"container_number" validate:"required,iso6346"`
+	Block              string     `json:"block" validate:"required"`
+	Slot               int        `json:"slot" validate:"required,min=1"`
+	Row                int        `json:"row" validate:"required,min=1"`
+	Tier               int        `json:"tier" validate:"required,min=1"`
+	ContainerSize      int        `json:"container_size" validate:"omitempty,oneof=10 20 40 45"`
+	ContainerHeight    float64    `json:"container_height" validate:"omitempty,container_height"`
+	ContainerType      string     `json:"container_type" validate:"omitempty,oneof=DRY REEFER OPEN_TOP FLAT_RACK TANK"`
+	SizeType           string     `json:"size_type"`
+	OutOfGauge         bool       `json:"out_of_gauge"`
+	GrossWeight        float64    `json:"gross_weight" validate:"omitempty,gt=0"`
+	ExpectedDeparture  *time.Time `json:"expected_departure"`
+	VesselVoyage       string     `json:"vessel_voyage"`
+	SupervisorOverride bool       `json:"supervisor_override"`
+	User               string     `json:"user"`
 }
 
+// MoveRequest relocates a placed container to another position in its yard.
+type MoveRequest struct {
+	Yard               string `json:"yard" validate:"required"`
+	ContainerNumber    string `json:"container_number" validate:"required"`
+	Block              string `json:"block" validate:"required"`
+	Slot               int    `json:"slot" validate:"required,min=1"`
+	Row                int    `json:"row" validate:"required,min=1"`
+	Tier               int    `json:"tier" validate:"required,min=1"`
+	Reason             string `json:"reason" validate:"required,oneof=RESTOW HOUSEKEEPING DAMAGE"`
+	SupervisorOverride bool   `json:"supervisor_override"`
+	User               string `json:"user"`
+}
+
+// PickupRequest lifts a container out of the yard. A container with others
+// stacked on it is refused unless PlanRehandles asks for the moves that would
+// clear it, or ExecuteRehandles performs them before the pickup.
 type PickupRequest struct {
-	Yard            string `json:"yard" validate:"required"`
-	ContainerNumber string `json:"container_number" validate:"required"`
+	Yard             string `json:"yard" validate:"required"`
+	ContainerNumber  string `json:"container_number" validate:"required"`
+	PlanRehandles    bool   `json:"plan_rehandles"`
+	ExecuteRehandles bool   `json:"execute_rehandles"`
+	User             string `json:"user"`
 }
 
 type Position struct {
@@ -46,8 +89,41 @@ type Position struct {
 	Tier  int    `json:"tier"`
 }
 
+// MaxSuggestionLimit caps the number of ranked positions a suggestion returns.
+const MaxSuggestionLimit = 20
+
+// RankedPosition is one of the alternative positions of a suggestion. Score is
+// in (0, 1], higher is better.
+type RankedPosition struct {
+	Position
+	YardPlanID uint     `json:"yard_plan_id"`
+	Score      float64  `json:"score"`
+	Reasons    []string `json:"reasons"`
+}
+
 type SuggestionResponse struct {
-	SuggestedPosition Position `json:"suggested_position"`
+	SuggestedPosition Position         `json:"suggested_position"`
+	ReservedUntil     *time.Time       `json:"reserved_until,omitempty"`
+	RankedPositions   []RankedPosition `json:"ranked_positions,omitempty"`
+}
+
+// Blocker is a container stacked on top of one being picked up.
+type Blocker struct {
+	ContainerNumber string `json:"container_number"`
+	Position
+}
+
+// RehandleMove relocates a blocker so a pickup can proceed.
+type RehandleMove struct {
+	ContainerNumber string   `json:"container_number"`
+	From            Position `json:"from"`
+	To              Position `json:"to"`
+}
+
+type PickupResponse struct {
+	Message   string         `json:"message"`
+	PickedUp  bool           `json:"picked_up"`
+	Rehandles []RehandleMove `json:"rehandles,omitempty"`
 }
 
 type MessageResponse struct {
@@ -61,13 +137,16 @@ func GetValidationError(err error) string {
 			case "Yard":
 				return "yard is required"
 			case "ContainerNumber":
+				if fieldError.Tag() == "iso6346" {
+					return "container_number " + CheckISO6346(fmt.Sprint(fieldError.Value())).Error()
+				}
 				return "container_number is required"
 			case "ContainerSize":
-				return "container_size must be either 20 or 40"
+				return "container_size must be one of: 10, 20, 40, 45"
 			case "ContainerHeight":
-				return "container_height must be either 8.6 or 9.6"
+				return "container_height must be one of: 8.0, 8.6, 9.0, 9.6"
 			case "ContainerType":
-				return "container_type must be one of: DRY, REEFER, OPEN_TOP"
+				return "container_type must be one of: DRY, REEFER, OPEN_TOP, FLAT_RACK, TANK"
 			case "Block":
 				return "block is required"
 			case "Slot":
@@ -76,6 +155,42 @@ func GetValidationError(err error) string {
 				return "row must be at least 1"
 			case "Tier":
 				return "tier must be at least 1"
+			case "Tiers":
+				return "tiers must be at least 1"
+			case "GrossWeight":
+				return "gross_weight must be greater than 0"
+			case "MinWeight":
+				return "min_weight must not be negative"
+			case "MaxWeight":
+				return "max_weight must be greater than min_weight, or 0 for no upper bound"
+			case "Name":
+				return "name is required"
+			case "MaxSlot":
+				return "max_slot must be at least 1"
+			case "MaxRow":
+				return "max_row must be at least 1"
+			case "MaxTier":
+				return "max_tier must be at least 1"
+			case "MaxStackHeight":
+				return "max_stack_height must be greater than 0"
+			case "StartSlot":
+				return "start_slot must be at least 1"
+			case "EndSlot":
+				return "end_slot must be at least 1 and not less than start_slot"
+			case "StartRow":
+				return "start_row must be at least 1"
+			case "EndRow":
+				return "end_row must be at least 1 and not less than start_row"
+			case "PriorityDirection":
+				return "priority_direction is required"
+			case "Priority":
+				return "priority must not be negative"
+			case "Status":
+				return "status must be one of: placed, picked_up"
+			case "Limit":
+				return "limit must be between 1 and 200"
+			case "Reason":
+				return "reason must be one of: RESTOW, HOUSEKEEPING, DAMAGE"
 			default:
 				return fmt.Sprintf("validation failed for field %s", fieldError.Field())
 			}
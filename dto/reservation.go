@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+// Reservation is an active suggestion holding a position for a container.
+type Reservation struct {
+	ContainerNumber string    `json:"container_number"`
+	Block           string    `json:"block"`
+	Slot            int       `json:"slot"`
+	Row             int       `json:"row"`
+	Tier            int       `json:"tier"`
+	ContainerSize   int       `json:"container_size"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
@@ -0,0 +1,108 @@
+package dto
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ISO 6346 length codes (first character of a size-type code) in feet.
+var sizeTypeLengths = map[byte]int{
+	'1': 10,
+	'2': 20,
+	'4': 40,
+	'L': 45,
+}
+
+// ISO 6346 height codes (second character) in feet.
+var sizeTypeHeights = map[byte]float64{
+	'0': 8.0,
+	'2': 8.6,
+	'4': 9.0,
+	'5': 9.6,
+}
+
+// ISO 6346 type groups (third character) mapped to our container types.
+var sizeTypeGroups = map[byte]string{
+	'G': "DRY", // general purpose
+	'V': "DRY", // ventilated
+	'B': "DRY", // dry bulk
+	'S': "DRY", // named cargo
+	'R': "REEFER",
+	'U': "OPEN_TOP",
+	'P': "FLAT_RACK",
+	'T': "TANK",
+}
+
+// SizeType is a decoded ISO 6346 size-type code such as 22G1 or 45R1.
+type SizeType struct {
+	Code   string
+	Length int
+	Height float64
+	Type   string
+}
+
+// DecodeSizeType decodes a 4 character ISO 6346 size-type code: length,
+// height, type group and a detail digit.
+func DecodeSizeType(code string) (SizeType, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 4 {
+		return SizeType{}, fmt.Errorf("size_type %q must be 4 characters, e.g. 22G1", code)
+	}
+
+	length, ok := sizeTypeLengths[code[0]]
+	if !ok {
+		return SizeType{}, fmt.Errorf("size_type %s: unsupported length code %q, expected 1, 2, 4 or L", code, string(code[0]))
+	}
+	height, ok := sizeTypeHeights[code[1]]
+	if !ok {
+		return SizeType{}, fmt.Errorf("size_type %s: unsupported height code %q, expected 0, 2, 4 or 5", code, string(code[1]))
+	}
+	containerType, ok := sizeTypeGroups[code[2]]
+	if !ok {
+		return SizeType{}, fmt.Errorf("size_type %s: unsupported type group %q, expected G, V, B, S, R, U, P or T", code, string(code[2]))
+	}
+	if code[3] < '0' || code[3] > '9' {
+		return SizeType{}, fmt.Errorf("size_type %s: detail code %q must be a digit", code, string(code[3]))
+	}
+
+	return SizeType{Code: code, Length: length, Height: height, Type: containerType}, nil
+}
+
+// applySizeType decodes code into the separate attribute fields. Fields the
+// client also sent explicitly must agree with the code.
+func applySizeType(code string, size *int, height *float64, containerType *string) (string, error) {
+	if code == "" {
+		return "", nil
+	}
+
+	decoded, err := DecodeSizeType(code)
+	if err != nil {
+		return "", err
+	}
+	if *size != 0 && *size != decoded.Length {
+		return "", fmt.Errorf("container_size %d conflicts with size_type %s (%dft)", *size, decoded.Code, decoded.Length)
+	}
+	if *height != 0 && *height != decoded.Height {
+		return "", fmt.Errorf("container_height %.1f conflicts with size_type %s (%.1fft)", *height, decoded.Code, decoded.Height)
+	}
+	if *containerType != "" && *containerType != decoded.Type {
+		return "", fmt.Errorf("container_type %s conflicts with size_type %s (%s)", *containerType, decoded.Code, decoded.Type)
+	}
+
+	*size, *height, *containerType = decoded.Length, decoded.Height, decoded.Type
+	return decoded.Code, nil
+}
+
+// ApplySizeType fills the container attributes from SizeType, if given.
+func (r *SuggestionRequest) ApplySizeType() error {
+	code, err := applySizeType(r.SizeType, &r.ContainerSize, &r.ContainerHeight, &r.ContainerType)
+	r.SizeType = code
+	return err
+}
+
+// ApplySizeType fills the container attributes from SizeType, if given.
+func (r *PlacementRequest) ApplySizeType() error {
+	code, err := applySizeType(r.SizeType, &r.ContainerSize, &r.ContainerHeight, &r.ContainerType)
+	r.SizeType = code
+	return err
+}
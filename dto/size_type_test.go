@@ -0,0 +1,111 @@
+package dto
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeSizeType(t *testing.T) {
+	tests := []struct {
+		code    string
+		want    SizeType
+		wantErr string // part of the error, "" when valid
+	}{
+		{code: "22G1", want: SizeType{Code: "22G1", Length: 20, Height: 8.6, Type: "DRY"}},
+		{code: "45R1", want: SizeType{Code: "45R1", Length: 40, Height: 9.6, Type: "REEFER"}},
+		{code: "L5G1", want: SizeType{Code: "L5G1", Length: 45, Height: 9.6, Type: "DRY"}},
+		{code: "22U1", want: SizeType{Code: "22U1", Length: 20, Height: 8.6, Type: "OPEN_TOP"}},
+		{code: "22P1", want: SizeType{Code: "22P1", Length: 20, Height: 8.6, Type: "FLAT_RACK"}},
+		{code: " 42g1 ", want: SizeType{Code: "42G1", Length: 40, Height: 8.6, Type: "DRY"}},
+		{code: "22G", wantErr: "must be 4 characters"},
+		{code: "22G10", wantErr: "must be 4 characters"},
+		{code: "32G1", wantErr: "unsupported length code"},
+		{code: "27G1", wantErr: "unsupported height code"},
+		{code: "22X1", wantErr: "unsupported type group"},
+		{code: "22GX", wantErr: "must be a digit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			got, err := DecodeSizeType(tt.code)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DecodeSizeType() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeSizeType() = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("DecodeSizeType() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplySizeType(t *testing.T) {
+	tests := []struct {
+		name    string
+		request SuggestionRequest
+		want    SuggestionRequest
+		wantErr string // part of the error, "" when valid
+	}{
+		{
+			name:    "fills the attributes",
+			request: SuggestionRequest{SizeType: "45r1"},
+			want:    SuggestionRequest{SizeType: "45R1", ContainerSize: 40, ContainerHeight: 9.6, ContainerType: "REEFER"},
+		},
+		{
+			name:    "agreeing attributes",
+			request: SuggestionRequest{SizeType: "22G1", ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY"},
+			want:    SuggestionRequest{SizeType: "22G1", ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY"},
+		},
+		{
+			name:    "no code",
+			request: SuggestionRequest{ContainerSize: 40, ContainerHeight: 9.6, ContainerType: "DRY"},
+			want:    SuggestionRequest{ContainerSize: 40, ContainerHeight: 9.6, ContainerType: "DRY"},
+		},
+		{
+			name:    "conflicting size",
+			request: SuggestionRequest{SizeType: "22G1", ContainerSize: 40},
+			wantErr: "container_size 40 conflicts with size_type 22G1",
+		},
+		{
+			name:    "conflicting height",
+			request: SuggestionRequest{SizeType: "22G1", ContainerHeight: 9.6},
+			wantErr: "container_height 9.6 conflicts with size_type 22G1",
+		},
+		{
+			name:    "conflicting type",
+			request: SuggestionRequest{SizeType: "22U1", ContainerType: "DRY"},
+			wantErr: "container_type DRY conflicts with size_type 22U1",
+		},
+		{
+			name:    "invalid code",
+			request: SuggestionRequest{SizeType: "99Z9", ContainerSize: 20},
+			wantErr: "unsupported length code",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := tt.request
+			err := request.ApplySizeType()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ApplySizeType() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				if request.ContainerSize != tt.request.ContainerSize || request.ContainerHeight != tt.request.ContainerHeight ||
+					request.ContainerType != tt.request.ContainerType {
+					t.Fatalf("ApplySizeType() changed the attributes to %+v on error", request)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplySizeType() = %v", err)
+			}
+			if request != tt.want {
+				t.Fatalf("ApplySizeType() = %+v, want %+v", request, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package dto
+
+type WeightClassRequest struct {
+	Name      string  `json:"name" validate:"required"`
+	MinWeight float64 `json:"min_weight" validate:"min=0"`
+	MaxWeight float64 `json:"max_weight" validate:"omitempty,gtfield=MinWeight"`
+}
+
+// WeightClassesRequest replaces the weight-class scheme of a yard.
+type WeightClassesRequest struct {
+	Classes []WeightClassRequest `json:"classes" validate:"dive"`
+}
+
+// WeightViolation is a pair of stacked containers where the upper one is
+// heavier than the one carrying it.
+type WeightViolation struct {
+	Block       string  `json:"block"`
+	Slot        int     `json:"slot"`
+	Row         int     `json:"row"`
+	LowerTier   int     `json:"lower_tier"`
+	Lower       string  `json:"lower_container"`
+	LowerWeight float64 `json:"lower_weight"`
+	LowerClass  string  `json:"lower_class,omitempty"`
+	Upper       string  `json:"upper_container"`
+	UpperWeight float64 `json:"upper_weight"`
+	UpperClass  string  `json:"upper_class,omitempty"`
+}
@@ -0,0 +1,59 @@
+package dto
+
+import "fmt"
+
+type YardRequest struct {
+	Name string `json:"name" validate:"required"`
+	// RelaxContainerNumberCheck accepts container numbers that fail ISO 6346,
+	// for yards holding legacy test data.
+	RelaxContainerNumberCheck bool `json:"relax_container_number_check"`
+}
+
+type BlockRequest struct {
+	Name                 string   `json:"name" validate:"required"`
+	MaxSlot              int      `json:"max_slot" validate:"required,min=1"`
+	MaxRow               int      `json:"max_row" validate:"required,min=1"`
+	MaxTier              int      `json:"max_tier" validate:"required,min=1"`
+	AllowFortyOnTwenties bool     `json:"allow_forty_on_twenties"`
+	MaxStackHeight       *float64 `json:"max_stack_height" validate:"omitempty,gt=0"`
+}
+
+type YardPlanRequest struct {
+	Block             string  `json:"block" validate:"required"`
+	ContainerSize     int     `json:"container_size" validate:"required,oneof=10 20 40 45"`
+	ContainerHeight   float64 `json:"container_height" validate:"required,container_height"`
+	ContainerType     string  `json:"container_type" validate:"required,oneof=DRY REEFER OPEN_TOP FLAT_RACK TANK"`
+	StartSlot         int     `json:"start_slot" validate:"required,min=1"`
+	EndSlot           int     `json:"end_slot" validate:"required,min=1,gtefield=StartSlot"`
+	StartRow          int     `json:"start_row" validate:"required,min=1"`
+	EndRow            int     `json:"end_row" validate:"required,min=1,gtefield=StartRow"`
+	PriorityDirection string  `json:"priority_direction" validate:"required"` // name of a registered placement strategy
+	Priority          int     `json:"priority" validate:"min=0"`
+	AllowOverlap      bool    `json:"allow_overlap"`
+	StackByWeight     bool    `json:"stack_by_weight"`
+}
+
+// PlanConflict describes an existing yard plan that clashes with the one being
+// created or updated.
+type PlanConflict struct {
+	PlanID       uint   `json:"plan_id"`
+	Block        string `json:"block"`
+	StartSlot    int    `json:"start_slot"`
+	EndSlot      int    `json:"end_slot"`
+	StartRow     int    `json:"start_row"`
+	EndRow       int    `json:"end_row"`
+	Priority     int    `json:"priority"`
+	AllowOverlap bool   `json:"allow_overlap"`
+}
+
+// CheckWithinBlock reports whether the plan area fits inside a block with the
+// given dimensions. Field-level rules are enforced by the validator tags.
+func (r YardPlanRequest) CheckWithinBlock(maxSlot, maxRow int) error {
+	if r.EndSlot > maxSlot {
+		return fmt.Errorf("end_slot %d exceeds block max_slot %d", r.EndSlot, maxSlot)
+	}
+	if r.EndRow > maxRow {
+		return fmt.Errorf("end_row %d exceeds block max_row %d", r.EndRow, maxRow)
+	}
+	return nil
+}
@@ -9,13 +9,27 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 
+	"backend_yard_planning_system/config"
 	"backend_yard_planning_system/controllers"
 	"backend_yard_planning_system/database"
+	"backend_yard_planning_system/repositories"
+	"backend_yard_planning_system/services"
 )
 
 func main() {
 
 	database.ConnectDB()
+	config.ConnectRedis()
+	defer config.CloseRedis()
+
+	store := repositories.NewGormStore(database.DB)
+	cache := services.NewRedisService(config.RedisClient)
+	yardService := services.NewYardService(store, cache)
+	managementService := services.NewYardManagementService(store, cache)
+
+	if config.RedisClient != nil {
+		go yardService.RunOccupancyReconciler(config.OccupancyReconcileInterval())
+	}
 
 	app := fiber.New(fiber.Config{
 		ErrorHandler: errorHandler,
@@ -30,14 +44,47 @@ func main() {
 		AllowHeaders: "Content-Type, Authorization",
 	}))
 
-	yardController := controllers.NewYardController()
+	yardController := controllers.NewYardController(yardService)
+	managementController := controllers.NewYardManagementController(managementService)
 
 	api := app.Group("/api")
 	{
 		api.Get("/yard-plans", yardController.GetYardPlans)
 		api.Post("/suggestion", yardController.GetSuggestion)
 		api.Post("/placement", yardController.PlaceContainer)
+		api.Post("/move", yardController.MoveContainer)
 		api.Post("/pickup", yardController.PickupContainer)
+		api.Get("/containers", yardController.SearchContainers)
+		api.Get("/containers/:number", yardController.GetContainer)
+		api.Get("/containers/:number/history", yardController.GetContainerHistory)
+	}
+
+	yards := api.Group("/yards")
+	{
+		yards.Get("/", managementController.ListYards)
+		yards.Post("/", managementController.CreateYard)
+		yards.Get("/:yard", managementController.GetYard)
+		yards.Put("/:yard", managementController.UpdateYard)
+		yards.Delete("/:yard", managementController.DeleteYard)
+		yards.Get("/:yard/weight-classes", managementController.GetWeightClasses)
+		yards.Put("/:yard/weight-classes", managementController.SetWeightClasses)
+		yards.Get("/:yard/weight-violations", yardController.GetWeightViolations)
+		yards.Get("/:yard/reservations", yardController.ListReservations)
+		yards.Delete("/:yard/reservations/:container", yardController.CancelReservation)
+
+		yards.Get("/:yard/blocks", managementController.ListBlocks)
+		yards.Post("/:yard/blocks", managementController.CreateBlock)
+		yards.Get("/:yard/blocks/:block", managementController.GetBlock)
+		yards.Put("/:yard/blocks/:block", managementController.UpdateBlock)
+		yards.Delete("/:yard/blocks/:block", managementController.DeleteBlock)
+		yards.Get("/:yard/blocks/:block/reefer-plugs", managementController.GetReeferPlugs)
+		yards.Put("/:yard/blocks/:block/reefer-plugs", managementController.SetReeferPlugs)
+
+		yards.Get("/:yard/plans", managementController.ListPlans)
+		yards.Post("/:yard/plans", managementController.CreatePlan)
+		yards.Get("/:yard/plans/:id", managementController.GetPlan)
+		yards.Put("/:yard/plans/:id", managementController.UpdatePlan)
+		yards.Delete("/:yard/plans/:id", managementController.DeletePlan)
 	}
 
 	app.Get("/health", func(c *fiber.Ctx) error {
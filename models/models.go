@@ -5,57 +5,147 @@ import (
 )
 
 type Yard struct {
+	ID                        uint      `gorm:"primaryKey" json:"id"`
+	Name                      string    `gorm:"uniqueIndex;not null" json:"name"`
+	RelaxContainerNumberCheck bool      `gorm:"not null;default:false" json:"relax_container_number_check"` // accept non ISO 6346 numbers
+	Blocks                    []Block   `json:"blocks,omitempty"`
+	CreatedAt                 time.Time `json:"created_at"`
+	UpdatedAt                 time.Time `json:"updated_at"`
+}
+
+type Block struct {
+	ID                   uint         `gorm:"primaryKey" json:"id"`
+	YardID               uint         `gorm:"not null;uniqueIndex:idx_blocks_yard_name" json:"yard_id"`
+	Yard                 Yard         `gorm:"foreignKey:YardID" json:"yard,omitempty"`
+	Name                 string       `gorm:"not null;uniqueIndex:idx_blocks_yard_name" json:"name"`
+	MaxSlot              int          `gorm:"not null" json:"max_slot"`
+	MaxRow               int          `gorm:"not null" json:"max_row"`
+	MaxTier              int          `gorm:"not null" json:"max_tier"`
+	AllowFortyOnTwenties bool         `gorm:"not null;default:false" json:"allow_forty_on_twenties"` // 40ft may rest on two 20ft
+	MaxStackHeight       *float64     `json:"max_stack_height,omitempty"`                            // crane clearance in feet, nil = tier count only
+	Plans                []YardPlan   `json:"plans,omitempty"`
+	ReeferPlugs          []ReeferPlug `json:"reefer_plugs,omitempty"`
+	Containers           []Container  `json:"containers,omitempty"`
+	CreatedAt            time.Time    `json:"created_at"`
+	UpdatedAt            time.Time    `json:"updated_at"`
+}
+
+// ReeferPlug is a power point at a (slot, row) ground position of a block that
+// can power reefers stacked up to Tiers high.
+type ReeferPlug struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `gorm:"uniqueIndex;not null" json:"name"`
-	Blocks    []Block   `json:"blocks,omitempty"`
+	BlockID   uint      `gorm:"not null;uniqueIndex:idx_reefer_plugs_position" json:"block_id"`
+	Slot      int       `gorm:"not null;uniqueIndex:idx_reefer_plugs_position" json:"slot"`
+	Row       int       `gorm:"not null;uniqueIndex:idx_reefer_plugs_position" json:"row"`
+	Tiers     int       `gorm:"not null" json:"tiers"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-type Block struct {
-	ID         uint        `gorm:"primaryKey" json:"id"`
-	YardID     uint        `gorm:"not null" json:"yard_id"`
-	Yard       Yard        `gorm:"foreignKey:YardID" json:"yard,omitempty"`
-	Name       string      `gorm:"not null" json:"name"`
-	MaxSlot    int         `gorm:"not null" json:"max_slot"`
-	MaxRow     int         `gorm:"not null" json:"max_row"`
-	MaxTier    int         `gorm:"not null" json:"max_tier"`
-	Plans      []YardPlan  `json:"plans,omitempty"`
-	Containers []Container `json:"containers,omitempty"`
-	CreatedAt  time.Time   `json:"created_at"`
-	UpdatedAt  time.Time   `json:"updated_at"`
+// WeightClass is one band of a yard's weight-class scheme. Containers are
+// compared by class rather than exact weight when a yard defines classes.
+type WeightClass struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	YardID    uint      `gorm:"not null;uniqueIndex:idx_weight_classes_yard_name" json:"yard_id"`
+	Name      string    `gorm:"not null;uniqueIndex:idx_weight_classes_yard_name" json:"name"`
+	MinWeight float64   `gorm:"not null" json:"min_weight"` // kg, inclusive
+	MaxWeight float64   `gorm:"not null" json:"max_weight"` // kg, exclusive; 0 = no upper bound
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type YardPlan struct {
 	ID                uint      `gorm:"primaryKey" json:"id"`
 	BlockID           uint      `gorm:"not null" json:"block_id"`
 	Block             Block     `gorm:"foreignKey:BlockID" json:"block,omitempty"`
-	ContainerSize     int       `gorm:"not null" json:"container_size"`   // 20 or 40
-	ContainerHeight   float64   `gorm:"not null" json:"container_height"` // 8.6 or 9.6
-	ContainerType     string    `gorm:"not null" json:"container_type"`   // DRY, REEFER, OPEN_TOP
+	ContainerSize     int       `gorm:"not null" json:"container_size"`   // 10, 20, 40 or 45
+	ContainerHeight   float64   `gorm:"not null" json:"container_height"` // 8.0, 8.6, 9.0 or 9.6
+	ContainerType     string    `gorm:"not null" json:"container_type"`   // DRY, REEFER, OPEN_TOP, FLAT_RACK, TANK
 	StartSlot         int       `gorm:"not null" json:"start_slot"`
 	EndSlot           int       `gorm:"not null" json:"end_slot"`
 	StartRow          int       `gorm:"not null" json:"start_row"`
 	EndRow            int       `gorm:"not null" json:"end_row"`
-	PriorityDirection string    `gorm:"not null" json:"priority_direction"` // LEFT_TO_RIGHT, BOTTOM_TO_TOP
+	PriorityDirection string    `gorm:"not null" json:"priority_direction"`            // placement strategy name, e.g. LEFT_TO_RIGHT
+	Priority          int       `gorm:"not null;default:0" json:"priority"`            // higher wins where plans overlap
+	AllowOverlap      bool      `gorm:"not null;default:false" json:"allow_overlap"`   // opt-in to sharing cells with other plans
+	StackByWeight     bool      `gorm:"not null;default:false" json:"stack_by_weight"` // prefer heavier containers below lighter ones
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
 }
 
+// Container is a box in the yard. The partial unique index guarantees at most
+// one placed container starts at any (block, slot, row, tier); overlapping
+// footprints of different start slots are prevented by placements locking
+// their block.
 type Container struct {
-	ID              uint       `gorm:"primaryKey" json:"id"`
-	ContainerNumber string     `gorm:"uniqueIndex;not null" json:"container_number"`
-	BlockID         uint       `gorm:"not null" json:"block_id"`
-	Block           Block      `gorm:"foreignKey:BlockID" json:"block,omitempty"`
-	ContainerSize   int        `gorm:"not null" json:"container_size"`
-	ContainerHeight float64    `gorm:"not null" json:"container_height"`
-	ContainerType   string     `gorm:"not null" json:"container_type"`
-	Slot            int        `gorm:"not null" json:"slot"`
-	Row             int        `gorm:"not null" json:"row"`
-	Tier            int        `gorm:"not null" json:"tier"`
-	IsPlaced        bool       `gorm:"not null;default:true" json:"is_placed"`
-	PlacedAt        time.Time  `json:"placed_at"`
-	PickedUpAt      *time.Time `json:"picked_up_at,omitempty"`
-	CreatedAt       time.Time  `json:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at"`
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	ContainerNumber   string     `gorm:"uniqueIndex;not null" json:"container_number"`
+	BlockID           uint       `gorm:"not null;uniqueIndex:idx_containers_placed_position,where:is_placed = true" json:"block_id"`
+	Block             Block      `gorm:"foreignKey:BlockID" json:"block,omitempty"`
+	ContainerSize     int        `gorm:"not null" json:"container_size"`
+	ContainerHeight   float64    `gorm:"not null" json:"container_height"`
+	ContainerType     string     `gorm:"not null" json:"container_type"`
+	SizeType          string     `json:"size_type,omitempty"` // ISO 6346 size-type code, when given
+	OutOfGauge        bool       `gorm:"not null;default:false" json:"out_of_gauge"`
+	GrossWeight       float64    `gorm:"not null;default:0" json:"gross_weight"` // kg, 0 when unknown
+	ExpectedDeparture *time.Time `json:"expected_departure,omitempty"`
+	VesselVoyage      string     `json:"vessel_voyage,omitempty"`
+	Slot              int        `gorm:"not null;uniqueIndex:idx_containers_placed_position,where:is_placed = true" json:"slot"` // first slot of the footprint
+	SlotSpan          int        `gorm:"not null;default:1" json:"slot_span"`                                                    // 1 for 10/20ft, 2 for 40/45ft
+	Row               int        `gorm:"not null;uniqueIndex:idx_containers_placed_position,where:is_placed = true" json:"row"`
+	Tier              int        `gorm:"not null;uniqueIndex:idx_containers_placed_position,where:is_placed = true" json:"tier"`
+	IsPlaced          bool       `gorm:"not null;default:true" json:"is_placed"`
+	PlacedAt          time.Time  `json:"placed_at"`
+	PickedUpAt        *time.Time `json:"picked_up_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// ContainerEvent is one entry of a container's history: a suggestion,
+// placement, move or pickup. Blocks are recorded by the name they had at the
+// time, so the trail survives renames and deletions.
+type ContainerEvent struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	ContainerNumber string    `gorm:"not null;index" json:"container_number"`
+	YardID          uint      `gorm:"not null" json:"yard_id"`
+	Type            string    `gorm:"not null" json:"type"` // SUGGESTED, PLACED, MOVED, PICKED_UP
+	FromBlock       string    `json:"from_block,omitempty"`
+	FromSlot        int       `json:"from_slot,omitempty"`
+	FromRow         int       `json:"from_row,omitempty"`
+	FromTier        int       `json:"from_tier,omitempty"`
+	ToBlock         string    `json:"to_block,omitempty"`
+	ToSlot          int       `json:"to_slot,omitempty"`
+	ToRow           int       `json:"to_row,omitempty"`
+	ToTier          int       `json:"to_tier,omitempty"`
+	User            string    `json:"user,omitempty"`
+	Reason          string    `json:"reason,omitempty"`
+	OccurredAt      time.Time `gorm:"not null;index" json:"occurred_at"`
+}
+
+// Suggestion records the latest position suggested for a container so that a
+// following placement can reuse the attributes it was suggested with. Until
+// ExpiresAt it also reserves the position: other suggestions skip it and only
+// this container may be placed there.
+type Suggestion struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	ContainerNumber   string     `gorm:"uniqueIndex;not null" json:"container_number"`
+	YardID            uint       `gorm:"not null" json:"yard_id"`
+	BlockID           uint       `gorm:"not null" json:"block_id"`
+	Block             Block      `gorm:"foreignKey:BlockID" json:"block,omitempty"`
+	YardPlanID        uint       `gorm:"not null" json:"yard_plan_id"`
+	ContainerSize     int        `gorm:"not null" json:"container_size"`
+	ContainerHeight   float64    `gorm:"not null" json:"container_height"`
+	ContainerType     string     `gorm:"not null" json:"container_type"`
+	SizeType          string     `json:"size_type,omitempty"` // ISO 6346 size-type code, when given
+	OutOfGauge        bool       `gorm:"not null;default:false" json:"out_of_gauge"`
+	GrossWeight       float64    `gorm:"not null;default:0" json:"gross_weight"`
+	ExpectedDeparture *time.Time `json:"expected_departure,omitempty"`
+	VesselVoyage      string     `json:"vessel_voyage,omitempty"`
+	Slot              int        `gorm:"not null" json:"slot"`
+	SlotSpan          int        `gorm:"not null;default:1" json:"slot_span"`
+	Row               int        `gorm:"not null" json:"row"`
+	Tier              int        `gorm:"not null" json:"tier"`
+	ExpiresAt         time.Time  `gorm:"index" json:"expires_at"` // end of the reservation
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
 }
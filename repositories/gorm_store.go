@@ -0,0 +1,431 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"backend_yard_planning_system/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormStore keeps the yard data in the database behind db. Writes never touch
+// the associations loaded into a record.
+type GormStore struct {
+	db *gorm.DB
+}
+
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+func (s *GormStore) Yards() YardRepository                { return gormYards{s.db} }
+func (s *GormStore) Blocks() BlockRepository              { return gormBlocks{s.db} }
+func (s *GormStore) Plans() PlanRepository                { return gormPlans{s.db} }
+func (s *GormStore) Containers() ContainerRepository      { return gormContainers{s.db} }
+func (s *GormStore) Suggestions() SuggestionRepository    { return gormSuggestions{s.db} }
+func (s *GormStore) Events() EventRepository              { return gormEvents{s.db} }
+func (s *GormStore) ReeferPlugs() ReeferPlugRepository    { return gormReeferPlugs{s.db} }
+func (s *GormStore) WeightClasses() WeightClassRepository { return gormWeightClasses{s.db} }
+
+func (s *GormStore) Transaction(fn func(tx Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&GormStore{db: tx})
+	})
+}
+
+// translateError maps GORM errors onto the errors of this package. The
+// database must be opened with TranslateError for duplicates to be detected.
+func translateError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fmt.Errorf("%w: %v", ErrDuplicate, err)
+	}
+	return err
+}
+
+// Yards
+
+type gormYards struct{ db *gorm.DB }
+
+func (r gormYards) List() ([]models.Yard, error) {
+	var yards []models.Yard
+	err := r.db.Order("name").Find(&yards).Error
+	return yards, translateError(err)
+}
+
+func (r gormYards) FindByName(name string) (*models.Yard, error) {
+	var yard models.Yard
+	if err := r.db.Where("name = ?", name).First(&yard).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &yard, nil
+}
+
+func (r gormYards) LockByName(name string) (*models.Yard, error) {
+	var yard models.Yard
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", name).First(&yard).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &yard, nil
+}
+
+func (r gormYards) Create(yard *models.Yard) error {
+	return translateError(r.db.Omit(clause.Associations).Create(yard).Error)
+}
+
+func (r gormYards) Save(yard *models.Yard) error {
+	return translateError(r.db.Omit(clause.Associations).Save(yard).Error)
+}
+
+func (r gormYards) Delete(id uint) error {
+	return translateError(r.db.Delete(&models.Yard{}, id).Error)
+}
+
+// Blocks
+
+type gormBlocks struct{ db *gorm.DB }
+
+func (r gormBlocks) List() ([]models.Block, error) {
+	var blocks []models.Block
+	err := r.db.Order("id").Find(&blocks).Error
+	return blocks, translateError(err)
+}
+
+func (r gormBlocks) ListByYard(yardID uint) ([]models.Block, error) {
+	var blocks []models.Block
+	err := r.db.Where("yard_id = ?", yardID).Order("name").Find(&blocks).Error
+	return blocks, translateError(err)
+}
+
+func (r gormBlocks) CountByYard(yardID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Block{}).Where("yard_id = ?", yardID).Count(&count).Error
+	return count, translateError(err)
+}
+
+func (r gormBlocks) FindByID(id uint) (*models.Block, error) {
+	var block models.Block
+	if err := r.db.First(&block, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &block, nil
+}
+
+func (r gormBlocks) FindByName(yardName, blockName string) (*models.Block, error) {
+	var block models.Block
+	err := r.db.Joins("JOIN yards ON yards.id = blocks.yard_id").
+		Where("yards.name = ? AND blocks.name = ?", yardName, blockName).
+		First(&block).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &block, nil
+}
+
+func (r gormBlocks) Lock(ids ...uint) error {
+	var locked []models.Block
+	return translateError(r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", ids).
+		Order("id").
+		Find(&locked).Error)
+}
+
+func (r gormBlocks) Create(block *models.Block) error {
+	return translateError(r.db.Omit(clause.Associations).Create(block).Error)
+}
+
+func (r gormBlocks) Save(block *models.Block) error {
+	return translateError(r.db.Omit(clause.Associations).Save(block).Error)
+}
+
+func (r gormBlocks) Delete(id uint) error {
+	return translateError(r.db.Delete(&models.Block{}, id).Error)
+}
+
+// Yard plans
+
+type gormPlans struct{ db *gorm.DB }
+
+func (r gormPlans) ListByYard(yardID uint) ([]models.YardPlan, error) {
+	var plans []models.YardPlan
+	err := r.db.Joins("JOIN blocks ON blocks.id = yard_plans.block_id").
+		Where("blocks.yard_id = ?", yardID).
+		Preload("Block").
+		Order("yard_plans.id").
+		Find(&plans).Error
+	return plans, translateError(err)
+}
+
+func (r gormPlans) ListByBlock(blockID uint) ([]models.YardPlan, error) {
+	var plans []models.YardPlan
+	err := r.db.Where("block_id = ?", blockID).
+		Preload("Block").
+		Order("id").
+		Find(&plans).Error
+	return plans, translateError(err)
+}
+
+func (r gormPlans) ListMatching(yardID uint, size int, height float64, containerType string) ([]models.YardPlan, error) {
+	var plans []models.YardPlan
+	err := r.db.Joins("JOIN blocks ON blocks.id = yard_plans.block_id").
+		Where("blocks.yard_id = ?", yardID).
+		Where("container_size = ?", size).
+		Where("container_type = ?", containerType).
+		Where("container_height > ? AND container_height < ?", height-heightTolerance, height+heightTolerance).
+		Order("yard_plans.priority DESC, yard_plans.id").
+		Preload("Block").
+		Find(&plans).Error
+	return plans, translateError(err)
+}
+
+func (r gormPlans) FindInYard(yardName string, id uint) (*models.YardPlan, error) {
+	var plan models.YardPlan
+	err := r.db.Joins("JOIN blocks ON blocks.id = yard_plans.block_id").
+		Joins("JOIN yards ON yards.id = blocks.yard_id").
+		Where("yards.name = ? AND yard_plans.id = ?", yardName, id).
+		Preload("Block").
+		First(&plan).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &plan, nil
+}
+
+func (r gormPlans) Create(plan *models.YardPlan) error {
+	return translateError(r.db.Omit(clause.Associations).Create(plan).Error)
+}
+
+func (r gormPlans) Save(plan *models.YardPlan) error {
+	return translateError(r.db.Omit(clause.Associations).Save(plan).Error)
+}
+
+func (r gormPlans) Delete(id uint) error {
+	return translateError(r.db.Delete(&models.YardPlan{}, id).Error)
+}
+
+func (r gormPlans) DeleteByBlock(blockID uint) error {
+	return translateError(r.db.Where("block_id = ?", blockID).Delete(&models.YardPlan{}).Error)
+}
+
+// Containers
+
+type gormContainers struct{ db *gorm.DB }
+
+func (r gormContainers) FindByNumber(number string) (*models.Container, error) {
+	var container models.Container
+	err := r.db.Where("container_number = ?", number).First(&container).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &container, nil
+}
+
+func (r gormContainers) FindInYard(number, yardName string) (*models.Container, error) {
+	var container models.Container
+	err := r.db.Joins("JOIN blocks ON blocks.id = containers.block_id").
+		Joins("JOIN yards ON yards.id = blocks.yard_id").
+		Where("containers.container_number = ? AND yards.name = ?", number, yardName).
+		First(&container).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &container, nil
+}
+
+func (r gormContainers) ListPlaced(blockID uint) ([]models.Container, error) {
+	var containers []models.Container
+	err := r.db.Where("block_id = ? AND is_placed = ?", blockID, true).
+		Order("tier, id").
+		Find(&containers).Error
+	return containers, translateError(err)
+}
+
+func (r gormContainers) ListPlacedInRow(blockID uint, row int) ([]models.Container, error) {
+	var containers []models.Container
+	err := r.db.Where("block_id = ? AND is_placed = ? AND row = ?", blockID, true, row).
+		Order("tier, id").
+		Find(&containers).Error
+	return containers, translateError(err)
+}
+
+func (r gormContainers) CountByBlock(blockID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Container{}).Where("block_id = ?", blockID).Count(&count).Error
+	return count, translateError(err)
+}
+
+func (r gormContainers) Search(filter ContainerFilter) ([]models.Container, error) {
+	tx := r.db.Model(&models.Container{}).
+		Joins("JOIN blocks ON blocks.id = containers.block_id").
+		Joins("JOIN yards ON yards.id = blocks.yard_id")
+
+	if filter.ContainerNumber != "" {
+		tx = tx.Where("containers.container_number = ?", filter.ContainerNumber)
+	}
+	if filter.Yard != "" {
+		tx = tx.Where("yards.name = ?", filter.Yard)
+	}
+	if filter.Block != "" {
+		tx = tx.Where("blocks.name = ?", filter.Block)
+	}
+	if filter.ContainerType != "" {
+		tx = tx.Where("containers.container_type = ?", filter.ContainerType)
+	}
+	if filter.ContainerSize != 0 {
+		tx = tx.Where("containers.container_size = ?", filter.ContainerSize)
+	}
+	if filter.Placed != nil {
+		tx = tx.Where("containers.is_placed = ?", *filter.Placed)
+	}
+	if filter.PlacedBefore != nil {
+		tx = tx.Where("containers.placed_at < ?", *filter.PlacedBefore)
+	}
+	if filter.PlacedAfter != nil {
+		tx = tx.Where("containers.placed_at > ?", *filter.PlacedAfter)
+	}
+	if filter.AfterID != 0 {
+		tx = tx.Where("containers.id > ?", filter.AfterID)
+	}
+	if filter.Limit > 0 {
+		tx = tx.Limit(filter.Limit)
+	}
+
+	var containers []models.Container
+	err := tx.Preload("Block.Yard").Order("containers.id").Find(&containers).Error
+	return containers, translateError(err)
+}
+
+func (r gormContainers) Create(container *models.Container) error {
+	return translateError(r.db.Omit(clause.Associations).Create(container).Error)
+}
+
+func (r gormContainers) Save(container *models.Container) error {
+	return translateError(r.db.Omit(clause.Associations).Save(container).Error)
+}
+
+// Suggestions
+
+type gormSuggestions struct{ db *gorm.DB }
+
+// active narrows a suggestion query to unexpired reservations.
+func (r gormSuggestions) active() *gorm.DB {
+	return r.db.Where("expires_at > ?", time.Now())
+}
+
+func (r gormSuggestions) FindByContainer(number string) (*models.Suggestion, error) {
+	var suggestion models.Suggestion
+	if err := r.db.Where("container_number = ?", number).First(&suggestion).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &suggestion, nil
+}
+
+func (r gormSuggestions) ListActiveByBlock(blockID uint) ([]models.Suggestion, error) {
+	var suggestions []models.Suggestion
+	err := r.active().Where("block_id = ?", blockID).Order("id").Find(&suggestions).Error
+	return suggestions, translateError(err)
+}
+
+func (r gormSuggestions) ListActiveByYard(yardID uint) ([]models.Suggestion, error) {
+	var suggestions []models.Suggestion
+	err := r.active().Where("yard_id = ?", yardID).
+		Preload("Block").
+		Order("expires_at").
+		Find(&suggestions).Error
+	return suggestions, translateError(err)
+}
+
+func (r gormSuggestions) Upsert(suggestion *models.Suggestion) error {
+	var existing models.Suggestion
+	err := r.db.Select("id, created_at").Where("container_number = ?", suggestion.ContainerNumber).First(&existing).Error
+	switch {
+	case err == nil:
+		suggestion.ID, suggestion.CreatedAt = existing.ID, existing.CreatedAt
+		return translateError(r.db.Omit(clause.Associations).Save(suggestion).Error)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return translateError(r.db.Omit(clause.Associations).Create(suggestion).Error)
+	}
+	return err
+}
+
+func (r gormSuggestions) Expire(yardID uint, containerNumber string) (bool, error) {
+	result := r.active().Model(&models.Suggestion{}).
+		Where("yard_id = ? AND container_number = ?", yardID, containerNumber).
+		Update("expires_at", time.Now())
+	return result.RowsAffected > 0, translateError(result.Error)
+}
+
+func (r gormSuggestions) DeleteByContainer(number string) error {
+	return translateError(r.db.Where("container_number = ?", number).Delete(&models.Suggestion{}).Error)
+}
+
+func (r gormSuggestions) DeleteByBlock(blockID uint) error {
+	return translateError(r.db.Where("block_id = ?", blockID).Delete(&models.Suggestion{}).Error)
+}
+
+// Container events
+
+type gormEvents struct{ db *gorm.DB }
+
+func (r gormEvents) ListByContainer(number string) ([]models.ContainerEvent, error) {
+	var events []models.ContainerEvent
+	err := r.db.Where("container_number = ?", number).
+		Order("occurred_at, id").
+		Find(&events).Error
+	return events, translateError(err)
+}
+
+func (r gormEvents) Create(event *models.ContainerEvent) error {
+	return translateError(r.db.Create(event).Error)
+}
+
+// Reefer plugs
+
+type gormReeferPlugs struct{ db *gorm.DB }
+
+func (r gormReeferPlugs) ListByBlock(blockID uint) ([]models.ReeferPlug, error) {
+	var plugs []models.ReeferPlug
+	err := r.db.Where("block_id = ?", blockID).Order("slot, row").Find(&plugs).Error
+	return plugs, translateError(err)
+}
+
+func (r gormReeferPlugs) Replace(blockID uint, plugs []models.ReeferPlug) error {
+	return translateError(r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("block_id = ?", blockID).Delete(&models.ReeferPlug{}).Error; err != nil {
+			return err
+		}
+		if len(plugs) == 0 {
+			return nil
+		}
+		return tx.Create(&plugs).Error
+	}))
+}
+
+// Weight classes
+
+type gormWeightClasses struct{ db *gorm.DB }
+
+func (r gormWeightClasses) ListByYard(yardID uint) ([]models.WeightClass, error) {
+	var classes []models.WeightClass
+	err := r.db.Where("yard_id = ?", yardID).Order("min_weight").Find(&classes).Error
+	return classes, translateError(err)
+}
+
+func (r gormWeightClasses) Replace(yardID uint, classes []models.WeightClass) error {
+	return translateError(r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("yard_id = ?", yardID).Delete(&models.WeightClass{}).Error; err != nil {
+			return err
+		}
+		if len(classes) == 0 {
+			return nil
+		}
+		return tx.Create(&classes).Error
+	}))
+}
@@ -0,0 +1,650 @@
+package repositories
+
+import (
+	"maps"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"backend_yard_planning_system/models"
+)
+
+// MemoryStore keeps the yard data in process, for tests and tools that run
+// the services without a database. Transactions run one at a time on a copy
+// of the data that replaces it when they commit, which also stands in for the
+// row locks of the database. It enforces the same unique constraints as the
+// schema but no foreign keys.
+type MemoryStore struct {
+	mu   *sync.Mutex
+	data *memoryData
+	inTx bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{mu: &sync.Mutex{}, data: &memoryData{}}
+}
+
+func (s *MemoryStore) Yards() YardRepository                { return memoryYards{s} }
+func (s *MemoryStore) Blocks() BlockRepository              { return memoryBlocks{s} }
+func (s *MemoryStore) Plans() PlanRepository                { return memoryPlans{s} }
+func (s *MemoryStore) Containers() ContainerRepository      { return memoryContainers{s} }
+func (s *MemoryStore) Suggestions() SuggestionRepository    { return memorySuggestions{s} }
+func (s *MemoryStore) Events() EventRepository              { return memoryEvents{s} }
+func (s *MemoryStore) ReeferPlugs() ReeferPlugRepository    { return memoryReeferPlugs{s} }
+func (s *MemoryStore) WeightClasses() WeightClassRepository { return memoryWeightClasses{s} }
+
+// Transaction runs fn on a copy of the data. A transaction started within
+// another one joins it.
+func (s *MemoryStore) Transaction(fn func(tx Store) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	working := s.data.clone()
+	if err := fn(&MemoryStore{mu: s.mu, data: working, inTx: true}); err != nil {
+		return err
+	}
+	*s.data = *working
+	return nil
+}
+
+// lock guards a single call made outside a transaction and returns its
+// unlock; within a transaction the store is already held.
+func (s *MemoryStore) lock() func() {
+	if s.inTx {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+type memoryData struct {
+	yards         table[models.Yard]
+	blocks        table[models.Block]
+	plans         table[models.YardPlan]
+	containers    table[models.Container]
+	suggestions   table[models.Suggestion]
+	events        table[models.ContainerEvent]
+	reeferPlugs   table[models.ReeferPlug]
+	weightClasses table[models.WeightClass]
+}
+
+func (d *memoryData) clone() *memoryData {
+	return &memoryData{
+		yards:         d.yards.clone(),
+		blocks:        d.blocks.clone(),
+		plans:         d.plans.clone(),
+		containers:    d.containers.clone(),
+		suggestions:   d.suggestions.clone(),
+		events:        d.events.clone(),
+		reeferPlugs:   d.reeferPlugs.clone(),
+		weightClasses: d.weightClasses.clone(),
+	}
+}
+
+// table holds the rows of one table by id, stored without associations.
+type table[T any] struct {
+	rows   map[uint]T
+	lastID uint
+}
+
+func (t table[T]) clone() table[T] {
+	return table[T]{rows: maps.Clone(t.rows), lastID: t.lastID}
+}
+
+func (t *table[T]) nextID() uint {
+	t.lastID++
+	return t.lastID
+}
+
+func (t *table[T]) put(id uint, row T) {
+	if t.rows == nil {
+		t.rows = make(map[uint]T)
+	}
+	t.rows[id] = row
+}
+
+// find returns the first row matching keep, in id order.
+func (t table[T]) find(keep func(T) bool) (T, bool) {
+	rows := t.filter(keep, nil)
+	if len(rows) == 0 {
+		var zero T
+		return zero, false
+	}
+	return rows[0], true
+}
+
+// filter returns the rows matching keep ordered by less, ties and a nil less
+// falling back to id order.
+func (t table[T]) filter(keep func(T) bool, less func(a, b T) bool) []T {
+	ids := make([]uint, 0, len(t.rows))
+	for id, row := range t.rows {
+		if keep == nil || keep(row) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	rows := make([]T, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, t.rows[id])
+	}
+	if less != nil {
+		sort.SliceStable(rows, func(i, j int) bool { return less(rows[i], rows[j]) })
+	}
+	return rows
+}
+
+func (t table[T]) count(keep func(T) bool) int64 {
+	var n int64
+	for _, row := range t.rows {
+		if keep(row) {
+			n++
+		}
+	}
+	return n
+}
+
+// stamp sets the timestamps GORM would set on a write.
+func stamp(createdAt, updatedAt *time.Time) {
+	now := time.Now()
+	if createdAt.IsZero() {
+		*createdAt = now
+	}
+	*updatedAt = now
+}
+
+// Yards
+
+type memoryYards struct{ s *MemoryStore }
+
+func (r memoryYards) List() ([]models.Yard, error) {
+	defer r.s.lock()()
+	return r.s.data.yards.filter(nil, func(a, b models.Yard) bool { return a.Name < b.Name }), nil
+}
+
+func (r memoryYards) FindByName(name string) (*models.Yard, error) {
+	defer r.s.lock()()
+	return r.s.data.yardByName(name)
+}
+
+func (r memoryYards) LockByName(name string) (*models.Yard, error) {
+	return r.FindByName(name)
+}
+
+func (r memoryYards) Create(yard *models.Yard) error {
+	yard.ID = 0
+	return r.Save(yard)
+}
+
+func (r memoryYards) Save(yard *models.Yard) error {
+	defer r.s.lock()()
+	d := r.s.data
+	if _, taken := d.yards.find(func(y models.Yard) bool { return y.Name == yard.Name && y.ID != yard.ID }); taken {
+		return ErrDuplicate
+	}
+	if yard.ID == 0 {
+		yard.ID = d.yards.nextID()
+	}
+	stamp(&yard.CreatedAt, &yard.UpdatedAt)
+
+	stored := *yard
+	stored.Blocks = nil
+	d.yards.put(stored.ID, stored)
+	return nil
+}
+
+func (r memoryYards) Delete(id uint) error {
+	defer r.s.lock()()
+	delete(r.s.data.yards.rows, id)
+	return nil
+}
+
+func (d *memoryData) yardByName(name string) (*models.Yard, error) {
+	yard, ok := d.yards.find(func(y models.Yard) bool { return y.Name == name })
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &yard, nil
+}
+
+// Blocks
+
+type memoryBlocks struct{ s *MemoryStore }
+
+func (r memoryBlocks) List() ([]models.Block, error) {
+	defer r.s.lock()()
+	return r.s.data.blocks.filter(nil, nil), nil
+}
+
+func (r memoryBlocks) ListByYard(yardID uint) ([]models.Block, error) {
+	defer r.s.lock()()
+	return r.s.data.blocks.filter(func(b models.Block) bool { return b.YardID == yardID },
+		func(a, b models.Block) bool { return a.Name < b.Name }), nil
+}
+
+func (r memoryBlocks) CountByYard(yardID uint) (int64, error) {
+	defer r.s.lock()()
+	return r.s.data.blocks.count(func(b models.Block) bool { return b.YardID == yardID }), nil
+}
+
+func (r memoryBlocks) FindByID(id uint) (*models.Block, error) {
+	defer r.s.lock()()
+	block, ok := r.s.data.blocks.rows[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &block, nil
+}
+
+func (r memoryBlocks) FindByName(yardName, blockName string) (*models.Block, error) {
+	defer r.s.lock()()
+	d := r.s.data
+	yard, err := d.yardByName(yardName)
+	if err != nil {
+		return nil, err
+	}
+	block, ok := d.blocks.find(func(b models.Block) bool { return b.YardID == yard.ID && b.Name == blockName })
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &block, nil
+}
+
+func (r memoryBlocks) Lock(ids ...uint) error {
+	return nil
+}
+
+func (r memoryBlocks) Create(block *models.Block) error {
+	block.ID = 0
+	return r.Save(block)
+}
+
+func (r memoryBlocks) Save(block *models.Block) error {
+	defer r.s.lock()()
+	d := r.s.data
+	if _, taken := d.blocks.find(func(b models.Block) bool {
+		return b.YardID == block.YardID && b.Name == block.Name && b.ID != block.ID
+	}); taken {
+		return ErrDuplicate
+	}
+	if block.ID == 0 {
+		block.ID = d.blocks.nextID()
+	}
+	stamp(&block.CreatedAt, &block.UpdatedAt)
+
+	stored := *block
+	stored.Yard = models.Yard{}
+	stored.Plans, stored.ReeferPlugs, stored.Containers = nil, nil, nil
+	d.blocks.put(stored.ID, stored)
+	return nil
+}
+
+func (r memoryBlocks) Delete(id uint) error {
+	defer r.s.lock()()
+	delete(r.s.data.blocks.rows, id)
+	return nil
+}
+
+// Yard plans
+
+type memoryPlans struct{ s *MemoryStore }
+
+// withBlocks loads the block of every plan.
+func (d *memoryData) withBlocks(plans []models.YardPlan) []models.YardPlan {
+	for i := range plans {
+		plans[i].Block = d.blocks.rows[plans[i].BlockID]
+	}
+	return plans
+}
+
+func (r memoryPlans) ListByYard(yardID uint) ([]models.YardPlan, error) {
+	defer r.s.lock()()
+	d := r.s.data
+	return d.withBlocks(d.plans.filter(func(p models.YardPlan) bool {
+		return d.blocks.rows[p.BlockID].YardID == yardID
+	}, nil)), nil
+}
+
+func (r memoryPlans) ListByBlock(blockID uint) ([]models.YardPlan, error) {
+	defer r.s.lock()()
+	d := r.s.data
+	return d.withBlocks(d.plans.filter(func(p models.YardPlan) bool { return p.BlockID == blockID }, nil)), nil
+}
+
+func (r memoryPlans) ListMatching(yardID uint, size int, height float64, containerType string) ([]models.YardPlan, error) {
+	defer r.s.lock()()
+	d := r.s.data
+	return d.withBlocks(d.plans.filter(func(p models.YardPlan) bool {
+		return d.blocks.rows[p.BlockID].YardID == yardID &&
+			p.ContainerSize == size &&
+			p.ContainerType == containerType &&
+			math.Abs(p.ContainerHeight-height) < heightTolerance
+	}, func(a, b models.YardPlan) bool { return a.Priority > b.Priority })), nil
+}
+
+func (r memoryPlans) FindInYard(yardName string, id uint) (*models.YardPlan, error) {
+	defer r.s.lock()()
+	d := r.s.data
+	plan, ok := d.plans.rows[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	yard, err := d.yardByName(yardName)
+	if err != nil || d.blocks.rows[plan.BlockID].YardID != yard.ID {
+		return nil, ErrNotFound
+	}
+	plan.Block = d.blocks.rows[plan.BlockID]
+	return &plan, nil
+}
+
+func (r memoryPlans) Create(plan *models.YardPlan) error {
+	plan.ID = 0
+	return r.Save(plan)
+}
+
+func (r memoryPlans) Save(plan *models.YardPlan) error {
+	defer r.s.lock()()
+	d := r.s.data
+	if plan.ID == 0 {
+		plan.ID = d.plans.nextID()
+	}
+	stamp(&plan.CreatedAt, &plan.UpdatedAt)
+
+	stored := *plan
+	stored.Block = models.Block{}
+	d.plans.put(stored.ID, stored)
+	return nil
+}
+
+func (r memoryPlans) Delete(id uint) error {
+	defer r.s.lock()()
+	delete(r.s.data.plans.rows, id)
+	return nil
+}
+
+func (r memoryPlans) DeleteByBlock(blockID uint) error {
+	defer r.s.lock()()
+	maps.DeleteFunc(r.s.data.plans.rows, func(_ uint, p models.YardPlan) bool { return p.BlockID == blockID })
+	return nil
+}
+
+// Containers
+
+type memoryContainers struct{ s *MemoryStore }
+
+// byTier orders containers from the lowest tier up.
+func byTier(a, b models.Container) bool {
+	return a.Tier < b.Tier
+}
+
+func (r memoryContainers) FindByNumber(number string) (*models.Container, error) {
+	defer r.s.lock()()
+	container, ok := r.s.data.containers.find(func(c models.Container) bool { return c.ContainerNumber == number })
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &container, nil
+}
+
+func (r memoryContainers) FindInYard(number, yardName string) (*models.Container, error) {
+	defer r.s.lock()()
+	d := r.s.data
+	yard, err := d.yardByName(yardName)
+	if err != nil {
+		return nil, err
+	}
+	container, ok := d.containers.find(func(c models.Container) bool {
+		return c.ContainerNumber == number && d.blocks.rows[c.BlockID].YardID == yard.ID
+	})
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &container, nil
+}
+
+func (r memoryContainers) ListPlaced(blockID uint) ([]models.Container, error) {
+	defer r.s.lock()()
+	return r.s.data.containers.filter(func(c models.Container) bool {
+		return c.BlockID == blockID && c.IsPlaced
+	}, byTier), nil
+}
+
+func (r memoryContainers) ListPlacedInRow(blockID uint, row int) ([]models.Container, error) {
+	defer r.s.lock()()
+	return r.s.data.containers.filter(func(c models.Container) bool {
+		return c.BlockID == blockID && c.IsPlaced && c.Row == row
+	}, byTier), nil
+}
+
+func (r memoryContainers) CountByBlock(blockID uint) (int64, error) {
+	defer r.s.lock()()
+	return r.s.data.containers.count(func(c models.Container) bool { return c.BlockID == blockID }), nil
+}
+
+func (r memoryContainers) Search(filter ContainerFilter) ([]models.Container, error) {
+	defer r.s.lock()()
+	d := r.s.data
+	containers := d.containers.filter(func(c models.Container) bool {
+		block, ok := d.blocks.rows[c.BlockID]
+		if !ok {
+			return false
+		}
+		yard, ok := d.yards.rows[block.YardID]
+		switch {
+		case !ok,
+			filter.ContainerNumber != "" && c.ContainerNumber != filter.ContainerNumber,
+			filter.Yard != "" && yard.Name != filter.Yard,
+			filter.Block != "" && block.Name != filter.Block,
+			filter.ContainerType != "" && c.ContainerType != filter.ContainerType,
+			filter.ContainerSize != 0 && c.ContainerSize != filter.ContainerSize,
+			filter.Placed != nil && c.IsPlaced != *filter.Placed,
+			filter.PlacedBefore != nil && !c.PlacedAt.Before(*filter.PlacedBefore),
+			filter.PlacedAfter != nil && !c.PlacedAt.After(*filter.PlacedAfter),
+			c.ID <= filter.AfterID:
+			return false
+		}
+		return true
+	}, nil)
+
+	if filter.Limit > 0 && len(containers) > filter.Limit {
+		containers = containers[:filter.Limit]
+	}
+	for i := range containers {
+		containers[i].Block = d.blocks.rows[containers[i].BlockID]
+		containers[i].Block.Yard = d.yards.rows[containers[i].Block.YardID]
+	}
+	return containers, nil
+}
+
+func (r memoryContainers) Create(container *models.Container) error {
+	container.ID = 0
+	return r.Save(container)
+}
+
+func (r memoryContainers) Save(container *models.Container) error {
+	defer r.s.lock()()
+	d := r.s.data
+	if _, taken := d.containers.find(func(c models.Container) bool {
+		if c.ID == container.ID {
+			return false
+		}
+		return c.ContainerNumber == container.ContainerNumber ||
+			(c.IsPlaced && container.IsPlaced && c.BlockID == container.BlockID &&
+				c.Slot == container.Slot && c.Row == container.Row && c.Tier == container.Tier)
+	}); taken {
+		return ErrDuplicate
+	}
+	if container.ID == 0 {
+		container.ID = d.containers.nextID()
+	}
+	stamp(&container.CreatedAt, &container.UpdatedAt)
+
+	stored := *container
+	stored.Block = models.Block{}
+	d.containers.put(stored.ID, stored)
+	return nil
+}
+
+// Suggestions
+
+type memorySuggestions struct{ s *MemoryStore }
+
+func active(s models.Suggestion) bool {
+	return s.ExpiresAt.After(time.Now())
+}
+
+func (r memorySuggestions) FindByContainer(number string) (*models.Suggestion, error) {
+	defer r.s.lock()()
+	suggestion, ok := r.s.data.suggestions.find(func(s models.Suggestion) bool { return s.ContainerNumber == number })
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &suggestion, nil
+}
+
+func (r memorySuggestions) ListActiveByBlock(blockID uint) ([]models.Suggestion, error) {
+	defer r.s.lock()()
+	return r.s.data.suggestions.filter(func(s models.Suggestion) bool {
+		return s.BlockID == blockID && active(s)
+	}, nil), nil
+}
+
+func (r memorySuggestions) ListActiveByYard(yardID uint) ([]models.Suggestion, error) {
+	defer r.s.lock()()
+	d := r.s.data
+	suggestions := d.suggestions.filter(func(s models.Suggestion) bool {
+		return s.YardID == yardID && active(s)
+	}, func(a, b models.Suggestion) bool { return a.ExpiresAt.Before(b.ExpiresAt) })
+	for i := range suggestions {
+		suggestions[i].Block = d.blocks.rows[suggestions[i].BlockID]
+	}
+	return suggestions, nil
+}
+
+func (r memorySuggestions) Upsert(suggestion *models.Suggestion) error {
+	defer r.s.lock()()
+	d := r.s.data
+	if existing, ok := d.suggestions.find(func(s models.Suggestion) bool {
+		return s.ContainerNumber == suggestion.ContainerNumber
+	}); ok {
+		suggestion.ID, suggestion.CreatedAt = existing.ID, existing.CreatedAt
+	} else {
+		suggestion.ID = d.suggestions.nextID()
+	}
+	stamp(&suggestion.CreatedAt, &suggestion.UpdatedAt)
+
+	stored := *suggestion
+	stored.Block = models.Block{}
+	d.suggestions.put(stored.ID, stored)
+	return nil
+}
+
+func (r memorySuggestions) Expire(yardID uint, containerNumber string) (bool, error) {
+	defer r.s.lock()()
+	d := r.s.data
+	suggestion, ok := d.suggestions.find(func(s models.Suggestion) bool {
+		return s.YardID == yardID && s.ContainerNumber == containerNumber && active(s)
+	})
+	if !ok {
+		return false, nil
+	}
+	suggestion.ExpiresAt = time.Now()
+	suggestion.UpdatedAt = suggestion.ExpiresAt
+	d.suggestions.put(suggestion.ID, suggestion)
+	return true, nil
+}
+
+func (r memorySuggestions) DeleteByContainer(number string) error {
+	defer r.s.lock()()
+	maps.DeleteFunc(r.s.data.suggestions.rows, func(_ uint, s models.Suggestion) bool { return s.ContainerNumber == number })
+	return nil
+}
+
+func (r memorySuggestions) DeleteByBlock(blockID uint) error {
+	defer r.s.lock()()
+	maps.DeleteFunc(r.s.data.suggestions.rows, func(_ uint, s models.Suggestion) bool { return s.BlockID == blockID })
+	return nil
+}
+
+// Container events
+
+type memoryEvents struct{ s *MemoryStore }
+
+func (r memoryEvents) ListByContainer(number string) ([]models.ContainerEvent, error) {
+	defer r.s.lock()()
+	return r.s.data.events.filter(func(e models.ContainerEvent) bool { return e.ContainerNumber == number },
+		func(a, b models.ContainerEvent) bool { return a.OccurredAt.Before(b.OccurredAt) }), nil
+}
+
+func (r memoryEvents) Create(event *models.ContainerEvent) error {
+	defer r.s.lock()()
+	event.ID = r.s.data.events.nextID()
+	r.s.data.events.put(event.ID, *event)
+	return nil
+}
+
+// Reefer plugs
+
+type memoryReeferPlugs struct{ s *MemoryStore }
+
+func (r memoryReeferPlugs) ListByBlock(blockID uint) ([]models.ReeferPlug, error) {
+	defer r.s.lock()()
+	return r.s.data.reeferPlugs.filter(func(p models.ReeferPlug) bool { return p.BlockID == blockID },
+		func(a, b models.ReeferPlug) bool { return a.Slot < b.Slot || (a.Slot == b.Slot && a.Row < b.Row) }), nil
+}
+
+func (r memoryReeferPlugs) Replace(blockID uint, plugs []models.ReeferPlug) error {
+	defer r.s.lock()()
+	d := r.s.data
+	seen := make(map[[2]int]bool, len(plugs))
+	for _, plug := range plugs {
+		position := [2]int{plug.Slot, plug.Row}
+		if plug.BlockID != blockID || seen[position] {
+			return ErrDuplicate
+		}
+		seen[position] = true
+	}
+
+	maps.DeleteFunc(d.reeferPlugs.rows, func(_ uint, p models.ReeferPlug) bool { return p.BlockID == blockID })
+	for i := range plugs {
+		plugs[i].ID = d.reeferPlugs.nextID()
+		stamp(&plugs[i].CreatedAt, &plugs[i].UpdatedAt)
+		d.reeferPlugs.put(plugs[i].ID, plugs[i])
+	}
+	return nil
+}
+
+// Weight classes
+
+type memoryWeightClasses struct{ s *MemoryStore }
+
+func (r memoryWeightClasses) ListByYard(yardID uint) ([]models.WeightClass, error) {
+	defer r.s.lock()()
+	return r.s.data.weightClasses.filter(func(c models.WeightClass) bool { return c.YardID == yardID },
+		func(a, b models.WeightClass) bool { return a.MinWeight < b.MinWeight }), nil
+}
+
+func (r memoryWeightClasses) Replace(yardID uint, classes []models.WeightClass) error {
+	defer r.s.lock()()
+	d := r.s.data
+	seen := make(map[string]bool, len(classes))
+	for _, class := range classes {
+		if class.YardID != yardID || seen[class.Name] {
+			return ErrDuplicate
+		}
+		seen[class.Name] = true
+	}
+
+	maps.DeleteFunc(d.weightClasses.rows, func(_ uint, c models.WeightClass) bool { return c.YardID == yardID })
+	for i := range classes {
+		classes[i].ID = d.weightClasses.nextID()
+		stamp(&classes[i].CreatedAt, &classes[i].UpdatedAt)
+		d.weightClasses.put(classes[i].ID, classes[i])
+	}
+	return nil
+}
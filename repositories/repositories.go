@@ -0,0 +1,156 @@
+// Package repositories holds the data access of the yard planning services:
+// one repository per table, reached through a Store that can also run them in
+// a transaction. GormStore keeps the data in the database; MemoryStore keeps
+// it in process, for running the services without any external service.
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"backend_yard_planning_system/models"
+)
+
+// heightTolerance is how far apart two container heights, stored as floats,
+// may be and still count as the same height.
+const heightTolerance = 0.01
+
+var (
+	// ErrNotFound is returned when a looked up record does not exist.
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate is returned when a write violates a unique constraint.
+	ErrDuplicate = errors.New("duplicated key not allowed")
+)
+
+// Store gives access to the repositories. Outside a transaction every call
+// stands on its own.
+type Store interface {
+	Yards() YardRepository
+	Blocks() BlockRepository
+	Plans() PlanRepository
+	Containers() ContainerRepository
+	Suggestions() SuggestionRepository
+	Events() EventRepository
+	ReeferPlugs() ReeferPlugRepository
+	WeightClasses() WeightClassRepository
+
+	// Transaction runs fn with a store whose writes are committed together
+	// when fn returns nil and rolled back otherwise.
+	Transaction(fn func(tx Store) error) error
+}
+
+type YardRepository interface {
+	// List returns all yards ordered by name.
+	List() ([]models.Yard, error)
+	FindByName(name string) (*models.Yard, error)
+	// LockByName finds a yard and locks it until the transaction ends.
+	LockByName(name string) (*models.Yard, error)
+	Create(yard *models.Yard) error
+	Save(yard *models.Yard) error
+	Delete(id uint) error
+}
+
+type BlockRepository interface {
+	// List returns all blocks of all yards in id order.
+	List() ([]models.Block, error)
+	// ListByYard returns the blocks of a yard ordered by name.
+	ListByYard(yardID uint) ([]models.Block, error)
+	CountByYard(yardID uint) (int64, error)
+	FindByID(id uint) (*models.Block, error)
+	FindByName(yardName, blockName string) (*models.Block, error)
+	// Lock locks the blocks until the transaction ends, in id order so that
+	// transactions locking the same blocks cannot deadlock.
+	Lock(ids ...uint) error
+	Create(block *models.Block) error
+	Save(block *models.Block) error
+	Delete(id uint) error
+}
+
+// PlanRepository returns yard plans with their block loaded.
+type PlanRepository interface {
+	// ListByYard returns the plans of a yard in id order.
+	ListByYard(yardID uint) ([]models.YardPlan, error)
+	// ListByBlock returns the plans of a block in id order.
+	ListByBlock(blockID uint) ([]models.YardPlan, error)
+	// ListMatching returns the plans of a yard for containers of the given
+	// size, height and type, highest priority first.
+	ListMatching(yardID uint, size int, height float64, containerType string) ([]models.YardPlan, error)
+	FindInYard(yardName string, id uint) (*models.YardPlan, error)
+	Create(plan *models.YardPlan) error
+	Save(plan *models.YardPlan) error
+	Delete(id uint) error
+	DeleteByBlock(blockID uint) error
+}
+
+// ContainerFilter narrows a container search. Zero fields do not filter.
+type ContainerFilter struct {
+	ContainerNumber string
+	Yard            string
+	Block           string
+	ContainerType   string
+	ContainerSize   int
+	Placed          *bool
+	PlacedBefore    *time.Time
+	PlacedAfter     *time.Time
+	AfterID         uint
+	Limit           int
+}
+
+type ContainerRepository interface {
+	FindByNumber(number string) (*models.Container, error)
+	FindInYard(number, yardName string) (*models.Container, error)
+	// ListPlaced returns the placed containers of a block, lowest tier first.
+	ListPlaced(blockID uint) ([]models.Container, error)
+	// ListPlacedInRow returns the placed containers of one row of a block,
+	// lowest tier first.
+	ListPlacedInRow(blockID uint, row int) ([]models.Container, error)
+	// CountByBlock counts the containers recorded in a block, placed or not.
+	CountByBlock(blockID uint) (int64, error)
+	// Search returns the containers matching filter in id order, with their
+	// block and yard loaded.
+	Search(filter ContainerFilter) ([]models.Container, error)
+	// Create and Save return ErrDuplicate when another placed container starts
+	// at the same block, slot, row and tier. Only the start slot is unique:
+	// the second slot of a 40ft container is guarded by the block lock alone.
+	Create(container *models.Container) error
+	Save(container *models.Container) error
+}
+
+// SuggestionRepository stores the latest suggestion of each container. A
+// suggestion is an active reservation until it expires.
+type SuggestionRepository interface {
+	FindByContainer(number string) (*models.Suggestion, error)
+	// ListActiveByBlock returns the active reservations of a block.
+	ListActiveByBlock(blockID uint) ([]models.Suggestion, error)
+	// ListActiveByYard returns the active reservations of a yard with their
+	// block loaded, soonest to expire first.
+	ListActiveByYard(yardID uint) ([]models.Suggestion, error)
+	// Upsert replaces the suggestion of the container.
+	Upsert(suggestion *models.Suggestion) error
+	// Expire ends the active reservation of a container in a yard, reporting
+	// whether there was one.
+	Expire(yardID uint, containerNumber string) (bool, error)
+	DeleteByContainer(number string) error
+	// DeleteByBlock drops the suggestions of a block, active or not.
+	DeleteByBlock(blockID uint) error
+}
+
+type EventRepository interface {
+	// ListByContainer returns the events of a container, oldest first.
+	ListByContainer(number string) ([]models.ContainerEvent, error)
+	Create(event *models.ContainerEvent) error
+}
+
+type ReeferPlugRepository interface {
+	// ListByBlock returns the plugs of a block ordered by slot and row.
+	ListByBlock(blockID uint) ([]models.ReeferPlug, error)
+	// Replace swaps all plugs of a block for plugs.
+	Replace(blockID uint, plugs []models.ReeferPlug) error
+}
+
+type WeightClassRepository interface {
+	// ListByYard returns the weight classes of a yard, lightest first.
+	ListByYard(yardID uint) ([]models.WeightClass, error)
+	// Replace swaps all weight classes of a yard for classes.
+	Replace(yardID uint, classes []models.WeightClass) error
+}
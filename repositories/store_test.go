@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"backend_yard_planning_system/database"
+	"backend_yard_planning_system/models"
+
+	"gorm.io/gorm/logger"
+)
+
+// testStores builds a fresh, empty store of every kind.
+var testStores = map[string]func(t *testing.T) Store{
+	"memory": func(*testing.T) Store { return NewMemoryStore() },
+	"sqlite": func(t *testing.T) Store {
+		db, err := database.Open(database.DriverSQLite, ":memory:")
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		db.Logger = logger.Default.LogMode(logger.Silent)
+		sqlDB, err := db.DB()
+		if err != nil {
+			t.Fatalf("DB: %v", err)
+		}
+		t.Cleanup(func() { sqlDB.Close() })
+		if err := database.Migrate(db); err != nil {
+			t.Fatalf("Migrate: %v", err)
+		}
+		return NewGormStore(db)
+	},
+}
+
+func TestPlacedContainersStartOnDistinctCells(t *testing.T) {
+	for name, newStore := range testStores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			yard := models.Yard{Name: "YRD1"}
+			if err := store.Yards().Create(&yard); err != nil {
+				t.Fatalf("Create yard: %v", err)
+			}
+			block := models.Block{YardID: yard.ID, Name: "LC01", MaxSlot: 10, MaxRow: 5, MaxTier: 4}
+			if err := store.Blocks().Create(&block); err != nil {
+				t.Fatalf("Create block: %v", err)
+			}
+
+			container := func(number string, size, slot int) *models.Container {
+				span := 1
+				if size == 40 {
+					span = 2
+				}
+				return &models.Container{
+					ContainerNumber: number, BlockID: block.ID,
+					ContainerSize: size, ContainerHeight: 8.6, ContainerType: "DRY",
+					Slot: slot, SlotSpan: span, Row: 1, Tier: 1, IsPlaced: true,
+				}
+			}
+			if err := store.Containers().Create(container("CONT0001", 20, 1)); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			if err := store.Containers().Create(container("CONT0002", 20, 1)); !errors.Is(err, ErrDuplicate) {
+				t.Fatalf("second placed container on the cell: %v, want ErrDuplicate", err)
+			}
+			moved := container("CONT0003", 20, 2)
+			if err := store.Containers().Create(moved); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			moved.Slot = 1
+			if err := store.Containers().Save(moved); !errors.Is(err, ErrDuplicate) {
+				t.Fatalf("moving a placed container onto the cell: %v, want ErrDuplicate", err)
+			}
+
+			// The index is partial: containers no longer placed do not count.
+			pickedUp := container("CONT0004", 20, 5)
+			if err := store.Containers().Create(pickedUp); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			pickedUp.Slot, pickedUp.IsPlaced = 1, false
+			if err := store.Containers().Save(pickedUp); err != nil {
+				t.Fatalf("picked up container on the cell: %v", err)
+			}
+			// Only start slots are unique; a 40ft reaching into a taken slot is
+			// left to the block lock.
+			if err := store.Containers().Create(container("CONT0005", 40, 3)); err != nil {
+				t.Fatalf("40ft starting next to a container: %v", err)
+			}
+			if err := store.Containers().Create(container("CONT0006", 20, 4)); err != nil {
+				t.Fatalf("20ft on the second slot of a 40ft: %v, want it accepted by the store", err)
+			}
+		})
+	}
+}
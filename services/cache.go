@@ -0,0 +1,172 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sort"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
+)
+
+// GetYardPlans returns the yard plans of a yard, read through the cache.
+func (s *YardService) GetYardPlans(yardName string) ([]models.YardPlan, error) {
+	if plans, err := s.cache.GetYardPlans(yardName); err == nil {
+		return plans, nil
+	}
+
+	yardPlans := []models.YardPlan{}
+	if yard, err := s.store.Yards().FindByName(yardName); err == nil {
+		if yardPlans, err = s.store.Plans().ListByYard(yard.ID); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+
+	s.cache.SetYardPlans(yardName, yardPlans)
+	return yardPlans, nil
+}
+
+// cachedPosition is a ranked position as stored in the suggestion cache.
+type cachedPosition struct {
+	PlanID  uint     `json:"plan_id"`
+	Slot    int      `json:"slot"`
+	Row     int      `json:"row"`
+	Tier    int      `json:"tier"`
+	Cost    float64  `json:"cost"`
+	Reasons []string `json:"reasons"`
+}
+
+// cacheableSuggestion reports whether a suggestion may be shared with other
+// containers of its class. Suggestions are keyed by size, height and type
+// only, so containers whose weight, departure or gauge change the ranking are
+// always ranked on their own.
+func cacheableSuggestion(attrs containerAttributes) bool {
+	return attrs.GrossWeight == 0 && attrs.ExpectedDeparture == nil && !attrs.OutOfGauge
+}
+
+// sharedSuggestions returns the ranked positions shared by every plain
+// suggestion of a container class, from the cache when the blocks of its
+// plans have not changed since. Identical requests arriving together share a
+// single ranking. Each request still skips the positions reserved since and
+// checks the one it picks.
+func (s *YardService) sharedSuggestions(yardName string, attrs containerAttributes) []cachedPosition {
+	yard, err := findYardByName(s.store, yardName)
+	if err != nil {
+		return nil
+	}
+	plans, err := matchingPlans(s.store, yard.ID, attrs)
+	if err != nil || len(plans) == 0 {
+		return nil
+	}
+
+	key, versioned := s.suggestionKey(yard.Name, plans, attrs)
+	result, err, shared := s.suggestions.Do(key, func() (interface{}, error) {
+		var cached []cachedPosition
+		if versioned && s.cache.GetCachedSuggestion(key, &cached) == nil {
+			return cached, nil
+		}
+
+		scale, err := loadWeightScale(s.store, yard.ID)
+		if err != nil {
+			return nil, err
+		}
+		ranked, err := s.rankAcrossPlans(s.store, plans, attrs, "", nil, scale, dto.MaxSuggestionLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		cached = make([]cachedPosition, 0, len(ranked))
+		for _, r := range ranked {
+			cached = append(cached, cachedPosition{
+				PlanID:  r.plan.ID,
+				Slot:    r.Slot,
+				Row:     r.Row,
+				Tier:    r.Tier,
+				Cost:    r.cost,
+				Reasons: r.reasons,
+			})
+		}
+		if versioned {
+			s.cache.CacheSuggestion(key, cached)
+		}
+		return cached, nil
+	})
+	if err != nil {
+		return nil
+	}
+	if shared {
+		log.Printf("🤝 Shared suggestion ranking %s", key)
+	}
+	return result.([]cachedPosition)
+}
+
+// suggestionKey builds the versioned cache key of a container class. Without
+// the block versions the key is only good for sharing rankings in flight.
+func (s *YardService) suggestionKey(yardName string, plans []models.YardPlan, attrs containerAttributes) (string, bool) {
+	seen := make(map[uint]bool)
+	var blockIDs []uint
+	for _, plan := range plans {
+		if !seen[plan.BlockID] {
+			seen[plan.BlockID] = true
+			blockIDs = append(blockIDs, plan.BlockID)
+		}
+	}
+	sort.Slice(blockIDs, func(i, j int) bool { return blockIDs[i] < blockIDs[j] })
+
+	versions, err := s.cache.BlockVersions(blockIDs)
+	versioned := err == nil
+	if !versioned {
+		versions = make([]int64, len(blockIDs))
+	}
+	return SuggestionKey(yardName, attrs.Size, attrs.Height, attrs.Type, blockIDs, versions), versioned
+}
+
+// firstSharedPosition returns the first of the shared positions that no
+// active reservation of another container holds. Reservations do not change a
+// block's version, so they are read from the database, once per block; the
+// rest of the position is checked by the caller.
+func firstSharedPosition(tx repositories.Store, plans []models.YardPlan, containerNumber string, shared []cachedPosition) (*rankedPosition, error) {
+	if len(shared) == 0 {
+		return nil, nil
+	}
+
+	plansByID := make(map[uint]models.YardPlan, len(plans))
+	for _, plan := range plans {
+		plansByID[plan.ID] = plan
+	}
+
+	reserved := make(map[uint]occupancyMap)
+	for _, c := range shared {
+		plan, ok := plansByID[c.PlanID]
+		if !ok {
+			continue
+		}
+		taken, loaded := reserved[plan.BlockID]
+		if !loaded {
+			footprints, err := reservedFootprints(tx, plan.BlockID, containerNumber)
+			if err != nil {
+				return nil, err
+			}
+			taken = make(occupancyMap)
+			for _, f := range footprints {
+				taken.mark(f)
+			}
+			reserved[plan.BlockID] = taken
+		}
+		if !taken.isFree(newFootprint(c.Slot, c.Row, c.Tier, plan.ContainerSize)) {
+			continue
+		}
+		return &rankedPosition{
+			position: position{Slot: c.Slot, Row: c.Row, Tier: c.Tier},
+			plan:     plan,
+			cost:     c.Cost,
+			reasons:  c.Reasons,
+		}, nil
+	}
+
+	log.Printf("ℹ️ Every shared suggestion is reserved, ranking for %s", containerNumber)
+	return nil, nil
+}
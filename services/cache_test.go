@@ -0,0 +1,281 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestCache returns a cache backed by an in-process Redis.
+func newTestCache(t *testing.T) (*miniredis.Miniredis, *RedisService) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, NewRedisService(client)
+}
+
+// cachedOccupancy decodes the cached occupancy of block, failing the test when
+// it is not cached.
+func cachedOccupancy(t *testing.T, cache *RedisService, block models.Block) occupancyMap {
+	t.Helper()
+	cached, err := cache.GetBlockOccupancy(block.ID)
+	if err != nil || !cached.Found {
+		t.Fatalf("GetBlockOccupancy = %+v, %v; want the cached maps", cached, err)
+	}
+	occupied, _, ok := decodeOccupancy(block, cached.Bitmap, cached.Heights)
+	if !ok {
+		t.Fatalf("cached maps of block %s do not fit it", block.Name)
+	}
+	return occupied
+}
+
+func TestYardPlansReadThrough(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		mr, cache := newTestCache(t)
+		s := newTestYard(t, store, cache)
+		key := fmt.Sprintf(CacheKeyYardPlans, "YRD1")
+
+		if mr.Exists(key) {
+			t.Fatalf("%s cached before the first read", key)
+		}
+		plans, err := s.GetYardPlans("YRD1")
+		if err != nil || len(plans) != 1 {
+			t.Fatalf("GetYardPlans on a miss = %d plans, %v; want 1", len(plans), err)
+		}
+		if !mr.Exists(key) {
+			t.Fatalf("%s not cached after a miss", key)
+		}
+
+		// A write that skips invalidation shows the read is served from Redis.
+		if err := store.Plans().Delete(plans[0].ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if plans, err := s.GetYardPlans("YRD1"); err != nil || len(plans) != 1 {
+			t.Fatalf("GetYardPlans on a hit = %d plans, %v; want the cached 1", len(plans), err)
+		}
+
+		cache.InvalidateYardPlans("YRD1")
+		if plans, err := s.GetYardPlans("YRD1"); err != nil || len(plans) != 0 {
+			t.Fatalf("GetYardPlans after invalidation = %d plans, %v; want 0", len(plans), err)
+		}
+	})
+}
+
+func TestBlockVersionFollowsPlacementAndPickup(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		mr, cache := newTestCache(t)
+		s := newTestYard(t, store, cache)
+		block, err := store.Blocks().FindByName("YRD1", "LC01")
+		if err != nil {
+			t.Fatalf("FindByName: %v", err)
+		}
+
+		version := func() int64 {
+			t.Helper()
+			versions, err := cache.BlockVersions([]uint{block.ID})
+			if err != nil {
+				t.Fatalf("BlockVersions: %v", err)
+			}
+			return versions[0]
+		}
+		suggestionKeys := func() []string {
+			var keys []string
+			for _, key := range mr.Keys() {
+				if strings.HasPrefix(key, "suggestions:") {
+					keys = append(keys, key)
+				}
+			}
+			return keys
+		}
+
+		if _, err := s.GetSuggestion(suggestionRequest("CONT0001"), 1); err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		before, keysBefore := version(), suggestionKeys()
+		if len(keysBefore) != 1 {
+			t.Fatalf("suggestion keys = %v, want 1", keysBefore)
+		}
+
+		place(t, s, "CONT0001", 1, 1, 1)
+		placed := version()
+		if placed <= before {
+			t.Fatalf("block version after placement = %d, want above %d", placed, before)
+		}
+		if cachedOccupancy(t, cache, *block).isFree(newFootprint(1, 1, 1, 20)) {
+			t.Fatal("cached bitmap does not show the placed container")
+		}
+
+		// The placement retired the cached ranking: the next one gets a new key.
+		response, err := s.GetSuggestion(suggestionRequest("CONT0002"), 1)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		wantPosition(t, response.SuggestedPosition, 2, 1, 1)
+		keysAfter := suggestionKeys()
+		if len(keysAfter) != 2 || !slices.Contains(keysAfter, keysBefore[0]) {
+			t.Fatalf("suggestion keys after placement = %v, want %s and a newer one", keysAfter, keysBefore[0])
+		}
+
+		if _, err := s.PickupContainer(dto.PickupRequest{Yard: "YRD1", ContainerNumber: "CONT0001"}); err != nil {
+			t.Fatalf("PickupContainer: %v", err)
+		}
+		if picked := version(); picked <= placed {
+			t.Fatalf("block version after pickup = %d, want above %d", picked, placed)
+		}
+		if !cachedOccupancy(t, cache, *block).isFree(newFootprint(1, 1, 1, 20)) {
+			t.Fatal("cached bitmap still shows the picked up container")
+		}
+	})
+}
+
+func TestFallsBackToStoreWhenRedisIsDown(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		mr, cache := newTestCache(t)
+		s := newTestYard(t, store, cache)
+		if _, err := s.GetYardPlans("YRD1"); err != nil {
+			t.Fatalf("GetYardPlans: %v", err)
+		}
+		place(t, s, "CONT0001", 1, 1, 1)
+
+		mr.Close()
+
+		if plans, err := s.GetYardPlans("YRD1"); err != nil || len(plans) != 1 {
+			t.Fatalf("GetYardPlans without Redis = %d plans, %v; want 1", len(plans), err)
+		}
+		response, err := s.GetSuggestion(suggestionRequest("CONT0002"), 1)
+		if err != nil {
+			t.Fatalf("GetSuggestion without Redis: %v", err)
+		}
+		wantPosition(t, response.SuggestedPosition, 2, 1, 1)
+		place(t, s, "CONT0002", 2, 1, 1)
+		wantCode(t, s.PlaceContainer(placementRequest("CONT0003", 1, 1, 1)), CodePositionOccupied)
+		if _, err := s.PickupContainer(dto.PickupRequest{Yard: "YRD1", ContainerNumber: "CONT0001"}); err != nil {
+			t.Fatalf("PickupContainer without Redis: %v", err)
+		}
+	})
+}
+
+func TestSuggestionRecoversFromStaleOccupancy(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		_, cache := newTestCache(t)
+		s := newTestYard(t, store, cache)
+		if err := s.ReconcileOccupancy(); err != nil {
+			t.Fatalf("ReconcileOccupancy: %v", err)
+		}
+		block, err := store.Blocks().FindByName("YRD1", "LC01")
+		if err != nil {
+			t.Fatalf("FindByName: %v", err)
+		}
+
+		// A placement whose cache update never landed.
+		stale := box("STALE01", 20, 1, 1, 1)
+		stale.BlockID = block.ID
+		if err := store.Containers().Create(&stale); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		response, err := s.GetSuggestion(suggestionRequest("CONT0001"), 1)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		wantPosition(t, response.SuggestedPosition, 2, 1, 1)
+		if cachedOccupancy(t, cache, *block).isFree(newFootprint(1, 1, 1, 20)) {
+			t.Fatal("stale cached occupancy was not rebuilt")
+		}
+	})
+}
+
+// countingStore counts the shared ranking loads: outside transactions only
+// that ranking reads the weight classes. Each load waits for release.
+type countingStore struct {
+	repositories.Store
+	loads   atomic.Int32
+	lookups atomic.Int32 // plan lookups ahead of the shared ranking
+	release chan struct{}
+}
+
+func (c *countingStore) WeightClasses() repositories.WeightClassRepository {
+	c.loads.Add(1)
+	<-c.release
+	return c.Store.WeightClasses()
+}
+
+func (c *countingStore) Plans() repositories.PlanRepository {
+	return countingPlans{c.Store.Plans(), c}
+}
+
+type countingPlans struct {
+	repositories.PlanRepository
+	store *countingStore
+}
+
+func (p countingPlans) ListMatching(yardID uint, size int, height float64, containerType string) ([]models.YardPlan, error) {
+	p.store.lookups.Add(1)
+	return p.PlanRepository.ListMatching(yardID, size, height, containerType)
+}
+
+func TestConcurrentSuggestionsShareOneRanking(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		const callers = 5
+		newTestYard(t, store, NewRedisService(nil))
+		counting := &countingStore{Store: store, release: make(chan struct{})}
+		s := NewYardService(counting, NewRedisService(nil))
+
+		var wg sync.WaitGroup
+		responses := make([]*dto.SuggestionResponse, callers)
+		errs := make([]error, callers)
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				responses[i], errs[i] = s.GetSuggestion(suggestionRequest(fmt.Sprintf("CONT%04d", i+1)), 1)
+			}(i)
+		}
+
+		// Hold the first load until every caller has looked up its plans and
+		// had time to join it.
+		for counting.lookups.Load() < callers {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(counting.release)
+		wg.Wait()
+
+		if loads := counting.loads.Load(); loads != 1 {
+			t.Fatalf("ranking loaded %d times, want once", loads)
+		}
+		seen := make(map[dto.Position]string)
+		for i, response := range responses {
+			if errs[i] != nil {
+				t.Fatalf("GetSuggestion: %v", errs[i])
+			}
+			number := fmt.Sprintf("CONT%04d", i+1)
+			if other, taken := seen[response.SuggestedPosition]; taken {
+				t.Fatalf("%s and %s were both suggested %+v", other, number, response.SuggestedPosition)
+			}
+			seen[response.SuggestedPosition] = number
+
+			reservation, err := store.Suggestions().FindByContainer(number)
+			if err != nil {
+				t.Fatalf("FindByContainer(%s): %v", number, err)
+			}
+			if reservation.Slot != response.SuggestedPosition.Slot || reservation.Row != response.SuggestedPosition.Row ||
+				reservation.Tier != response.SuggestedPosition.Tier {
+				t.Fatalf("%s reserved %d/%d/%d, suggested %+v", number,
+					reservation.Slot, reservation.Row, reservation.Tier, response.SuggestedPosition)
+			}
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
+)
+
+// RelaxesContainerNumbers reports whether a yard accepts container numbers
+// that fail ISO 6346. Unknown yards do not.
+func (s *YardService) RelaxesContainerNumbers(yardName string) bool {
+	yard, err := s.store.Yards().FindByName(yardName)
+	if err != nil {
+		return false
+	}
+	return yard.RelaxContainerNumberCheck
+}
+
+// GetContainer returns where a container is, or was last, in the yard.
+func (s *YardService) GetContainer(containerNumber string) (*dto.ContainerResponse, error) {
+	containers, err := s.store.Containers().Search(repositories.ContainerFilter{ContainerNumber: containerNumber, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(containers) == 0 {
+		return nil, newNotFoundError("container_not_found", fmt.Sprintf("container %s not found", containerNumber))
+	}
+
+	response := containerResponse(containers[0])
+	return &response, nil
+}
+
+// SearchContainers lists containers matching the query in id order, one page
+// at a time. The cursor encodes the id of the last container of the previous
+// page, so pages stay stable while containers are placed.
+func (s *YardService) SearchContainers(query dto.ContainerSearchQuery) (*dto.ContainerSearchResponse, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = dto.DefaultContainerSearchLimit
+	}
+
+	// One extra row tells whether another page follows.
+	filter := repositories.ContainerFilter{
+		Yard:          query.Yard,
+		Block:         query.Block,
+		ContainerType: query.ContainerType,
+		ContainerSize: query.ContainerSize,
+		Limit:         limit + 1,
+	}
+	switch query.Status {
+	case dto.ContainerStatusPlaced:
+		placed := true
+		filter.Placed = &placed
+	case dto.ContainerStatusPickedUp:
+		placed := false
+		filter.Placed = &placed
+	}
+	if query.PlacedBefore != "" {
+		before, err := time.Parse(time.RFC3339Nano, query.PlacedBefore)
+		if err != nil {
+			return nil, newInvalidError("invalid_placed_before", "placed_before must be an RFC 3339 timestamp")
+		}
+		filter.PlacedBefore = &before
+	}
+	if query.PlacedAfter != "" {
+		after, err := time.Parse(time.RFC3339Nano, query.PlacedAfter)
+		if err != nil {
+			return nil, newInvalidError("invalid_placed_after", "placed_after must be an RFC 3339 timestamp")
+		}
+		filter.PlacedAfter = &after
+	}
+	if query.Cursor != "" {
+		afterID, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, newInvalidError("invalid_cursor", "cursor is not valid")
+		}
+		filter.AfterID = afterID
+	}
+
+	containers, err := s.store.Containers().Search(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.ContainerSearchResponse{Containers: make([]dto.ContainerResponse, 0, len(containers))}
+	if len(containers) > limit {
+		containers = containers[:limit]
+		response.NextCursor = encodeCursor(containers[limit-1].ID)
+	}
+	for _, container := range containers {
+		response.Containers = append(response.Containers, containerResponse(container))
+	}
+	return response, nil
+}
+
+func containerResponse(container models.Container) dto.ContainerResponse {
+	status := dto.ContainerStatusPickedUp
+	if container.IsPlaced {
+		status = dto.ContainerStatusPlaced
+	}
+	return dto.ContainerResponse{
+		ContainerNumber:   container.ContainerNumber,
+		Yard:              container.Block.Yard.Name,
+		Block:             container.Block.Name,
+		Slot:              container.Slot,
+		SlotSpan:          containerFootprint(container).Span,
+		Row:               container.Row,
+		Tier:              container.Tier,
+		ContainerSize:     container.ContainerSize,
+		ContainerHeight:   container.ContainerHeight,
+		ContainerType:     container.ContainerType,
+		SizeType:          container.SizeType,
+		OutOfGauge:        container.OutOfGauge,
+		GrossWeight:       container.GrossWeight,
+		ExpectedDeparture: container.ExpectedDeparture,
+		VesselVoyage:      container.VesselVoyage,
+		Status:            status,
+		PlacedAt:          container.PlacedAt,
+		PickedUpAt:        container.PickedUpAt,
+	}
+}
+
+func encodeCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(id), 10)))
+}
+
+func decodeCursor(cursor string) (uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
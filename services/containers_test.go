@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/repositories"
+)
+
+// TestSearchContainersPages walks every page and checks that each container
+// is listed exactly once, in id order, even when one is placed mid-walk.
+func TestSearchContainersPages(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+		var want []string
+		for i := 0; i < 7; i++ {
+			number := fmt.Sprintf("CONT%04d", i+1)
+			place(t, s, number, i%3+1, i/3+1, 1)
+			want = append(want, number)
+		}
+
+		var got []string
+		query := dto.ContainerSearchQuery{Yard: "YRD1", Limit: 3}
+		for pages := 1; ; pages++ {
+			page, err := s.SearchContainers(query)
+			if err != nil {
+				t.Fatalf("SearchContainers: %v", err)
+			}
+			if len(page.Containers) > query.Limit {
+				t.Fatalf("page %d has %d containers, limit %d", pages, len(page.Containers), query.Limit)
+			}
+			for _, container := range page.Containers {
+				got = append(got, container.ContainerNumber)
+			}
+			if pages == 1 {
+				// Placed after the walk started, so it shows up on the last page.
+				place(t, s, "CONT0008", 1, 4, 1)
+				want = append(want, "CONT0008")
+			}
+			if page.NextCursor == "" {
+				if pages != 3 {
+					t.Fatalf("walked %d pages, want 3", pages)
+				}
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("listed %v, want %v", got, want)
+		}
+	})
+}
+
+func TestSearchContainersPlacedTimes(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+		place(t, s, "CONT0001", 1, 1, 1)
+
+		page, err := s.SearchContainers(dto.ContainerSearchQuery{PlacedAfter: "2000-01-01T00:00:00.123456789Z", PlacedBefore: "2999-01-01T00:00:00+02:00"})
+		if err != nil {
+			t.Fatalf("SearchContainers: %v", err)
+		}
+		if len(page.Containers) != 1 {
+			t.Fatalf("listed %d containers, want 1", len(page.Containers))
+		}
+
+		page, err = s.SearchContainers(dto.ContainerSearchQuery{PlacedBefore: "2000-01-01T00:00:00.5Z"})
+		if err != nil {
+			t.Fatalf("SearchContainers: %v", err)
+		}
+		if len(page.Containers) != 0 {
+			t.Fatalf("listed %d containers placed before 2000, want 0", len(page.Containers))
+		}
+
+		_, err = s.SearchContainers(dto.ContainerSearchQuery{PlacedBefore: "2026-01-01"})
+		wantCode(t, err, "invalid_placed_before")
+		_, err = s.SearchContainers(dto.ContainerSearchQuery{PlacedAfter: "yesterday"})
+		wantCode(t, err, "invalid_placed_after")
+		if errorKind(err) != KindInvalid {
+			t.Fatalf("error kind = %v, want KindInvalid", errorKind(err))
+		}
+	})
+}
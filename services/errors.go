@@ -0,0 +1,37 @@
+package services
+
+// ErrorKind classifies a ServiceError so the HTTP layer can pick a status code
+// without parsing messages.
+type ErrorKind int
+
+const (
+	KindInvalid ErrorKind = iota
+	KindNotFound
+	KindConflict
+	KindRuleViolation
+)
+
+// ServiceError is returned by services when the caller needs more than a plain
+// message: a stable machine readable code and optional details.
+type ServiceError struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Details interface{}
+}
+
+func (e *ServiceError) Error() string {
+	return e.Message
+}
+
+func newInvalidError(code, message string) *ServiceError {
+	return &ServiceError{Kind: KindInvalid, Code: code, Message: message}
+}
+
+func newNotFoundError(code, message string) *ServiceError {
+	return &ServiceError{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func newConflictError(code, message string) *ServiceError {
+	return &ServiceError{Kind: KindConflict, Code: code, Message: message}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
+)
+
+// footprint is the set of cells a container occupies: Span consecutive slots
+// starting at StartSlot, on a single row and tier. A 40ft container spans two
+// 20ft slots; a 45ft sits on the corner castings of a 40ft position and a
+// 10ft takes a whole 20ft slot.
+type footprint struct {
+	StartSlot int
+	Span      int
+	Row       int
+	Tier      int
+}
+
+func slotSpanForSize(containerSize int) int {
+	if containerSize >= 40 {
+		return 2
+	}
+	return 1
+}
+
+func newFootprint(slot, row, tier, containerSize int) footprint {
+	return footprint{StartSlot: slot, Span: slotSpanForSize(containerSize), Row: row, Tier: tier}
+}
+
+func containerFootprint(container models.Container) footprint {
+	span := container.SlotSpan
+	if span < 1 {
+		span = slotSpanForSize(container.ContainerSize)
+	}
+	return footprint{StartSlot: container.Slot, Span: span, Row: container.Row, Tier: container.Tier}
+}
+
+func (f footprint) EndSlot() int {
+	return f.StartSlot + f.Span - 1
+}
+
+// stackAt returns the containers covering a slot of f on its row at the tiers
+// keep accepts, in the order given.
+func stackAt(containers []models.Container, f footprint, keep func(tier int) bool) []models.Container {
+	var stack []models.Container
+	for _, container := range containers {
+		c := containerFootprint(container)
+		if c.Row == f.Row && keep(c.Tier) && c.StartSlot <= f.EndSlot() && c.EndSlot() >= f.StartSlot {
+			stack = append(stack, container)
+		}
+	}
+	return stack
+}
+
+const CodePositionOccupied = "position_occupied"
+
+// findOccupant returns the container of row overlapping f, or nil when all of
+// its cells are free.
+func findOccupant(row []models.Container, f footprint) *models.Container {
+	occupants := stackAt(row, f, func(tier int) bool { return tier == f.Tier })
+	if len(occupants) == 0 {
+		return nil
+	}
+	return &occupants[0]
+}
+
+// containersAbove returns the placed containers stacked above f, lowest first.
+func containersAbove(tx repositories.Store, blockID uint, f footprint) ([]models.Container, error) {
+	row, err := tx.Containers().ListPlacedInRow(blockID, f.Row)
+	if err != nil {
+		return nil, err
+	}
+	return stackAt(row, f, func(tier int) bool { return tier > f.Tier }), nil
+}
+
+// occupancyMap marks every cell covered by the given containers.
+type occupancyMap map[string]bool
+
+func newOccupancyMap(containers []models.Container) occupancyMap {
+	occupied := make(occupancyMap)
+	for _, container := range containers {
+		occupied.mark(containerFootprint(container))
+	}
+	return occupied
+}
+
+func (m occupancyMap) mark(f footprint) {
+	for slot := f.StartSlot; slot <= f.EndSlot(); slot++ {
+		m[getPositionKey(slot, f.Row, f.Tier)] = true
+	}
+}
+
+func (m occupancyMap) isFree(f footprint) bool {
+	for slot := f.StartSlot; slot <= f.EndSlot(); slot++ {
+		if m[getPositionKey(slot, f.Row, f.Tier)] {
+			return false
+		}
+	}
+	return true
+}
+
+// hasAbove reports whether any cell above f, up to maxTier, is occupied.
+func (m occupancyMap) hasAbove(f footprint, maxTier int) bool {
+	for tier := f.Tier + 1; tier <= maxTier; tier++ {
+		above := f
+		above.Tier = tier
+		if !m.isFree(above) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockStacks is the StackView of the placed containers of a block area.
+type blockStacks struct {
+	occupied   occupancyMap
+	containers []models.Container
+	maxTier    int
+}
+
+func newBlockStacks(containers []models.Container, maxTier int) blockStacks {
+	return blockStacks{occupied: newOccupancyMap(containers), containers: containers, maxTier: maxTier}
+}
+
+func (b blockStacks) StackHeight(slot, row int) int {
+	for tier := b.maxTier; tier >= 1; tier-- {
+		if b.occupied[getPositionKey(slot, row, tier)] {
+			return tier
+		}
+	}
+	return 0
+}
+
+func (b blockStacks) Below(slot, row, tier int) []models.Container {
+	var below []models.Container
+	for _, container := range b.containers {
+		f := containerFootprint(container)
+		if f.Row == row && f.Tier < tier && slot >= f.StartSlot && slot <= f.EndSlot() {
+			below = append(below, container)
+		}
+	}
+	return below
+}
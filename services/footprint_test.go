@@ -0,0 +1,157 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"backend_yard_planning_system/models"
+)
+
+// box is a placed container of the given size with its first slot at slot.
+func box(number string, size, slot, row, tier int) models.Container {
+	return models.Container{
+		ContainerNumber: number,
+		ContainerSize:   size,
+		ContainerHeight: 8.6,
+		ContainerType:   "DRY",
+		Slot:            slot,
+		SlotSpan:        slotSpanForSize(size),
+		Row:             row,
+		Tier:            tier,
+		IsPlaced:        true,
+	}
+}
+
+// errorCode returns the code of a ServiceError, or "" for nil and other errors.
+func errorCode(err error) string {
+	var serviceErr *ServiceError
+	if errors.As(err, &serviceErr) {
+		return serviceErr.Code
+	}
+	return ""
+}
+
+// errorKind returns the kind of a ServiceError, or -1 for nil and other errors.
+func errorKind(err error) ErrorKind {
+	var serviceErr *ServiceError
+	if errors.As(err, &serviceErr) {
+		return serviceErr.Kind
+	}
+	return -1
+}
+
+func TestCheckStackSupportMixedSizes(t *testing.T) {
+	twenties := []models.Container{box("T1", 20, 1, 1, 1), box("T2", 20, 2, 1, 1)}
+	forty := []models.Container{box("F1", 40, 1, 1, 1)}
+
+	tests := []struct {
+		name       string
+		f          footprint
+		size       int
+		below      []models.Container
+		allowForty bool
+		wantCode   string
+	}{
+		{"ground tier needs no support", newFootprint(1, 1, 1, 40), 40, nil, false, ""},
+		{"40ft on two 20fts when allowed", newFootprint(1, 1, 2, 40), 40, twenties, true, ""},
+		{"40ft on two 20fts when not allowed", newFootprint(1, 1, 2, 40), 40, twenties, false, CodeFortyOnTwentiesBlocked},
+		{"40ft on a single 20ft", newFootprint(1, 1, 2, 40), 40, twenties[:1], true, CodeUnsupportedStack},
+		{"40ft on a 40ft", newFootprint(1, 1, 2, 40), 40, forty, false, ""},
+		{"40ft offset on a 40ft", newFootprint(2, 1, 2, 40), 40, forty, false, CodeMidSpanStack},
+		{"20ft on the first half of a 40ft", newFootprint(1, 1, 2, 20), 20, forty, false, CodeMidSpanStack},
+		{"20ft on the mid-span of a 40ft", newFootprint(2, 1, 2, 20), 20, forty, false, CodeMidSpanStack},
+		{"20ft on a 20ft", newFootprint(2, 1, 2, 20), 20, twenties, false, ""},
+		{"20ft on another row", newFootprint(1, 2, 2, 20), 20, twenties, false, CodeUnsupportedStack},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkStackSupport(tt.f, tt.size, tt.below, tt.allowForty)
+			if got := errorCode(err); got != tt.wantCode {
+				t.Fatalf("checkStackSupport() code = %q (%v), want %q", got, err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestFindOccupantMixedSizes(t *testing.T) {
+	// A 20ft at slot 1 next to a 40ft over slots 2-3, and a 20ft on tier 2.
+	row := []models.Container{box("T1", 20, 1, 1, 1), box("F1", 40, 2, 1, 1), box("T2", 20, 1, 1, 2)}
+
+	tests := []struct {
+		name string
+		f    footprint
+		want string
+	}{
+		{"20ft on the 20ft", newFootprint(1, 1, 1, 20), "T1"},
+		{"20ft on the first half of the 40ft", newFootprint(2, 1, 1, 20), "F1"},
+		{"20ft on the second half of the 40ft", newFootprint(3, 1, 1, 20), "F1"},
+		{"20ft next to the 40ft", newFootprint(4, 1, 1, 20), ""},
+		{"40ft over the 20ft and the 40ft", newFootprint(1, 1, 1, 40), "T1"},
+		{"40ft overlapping the 40ft", newFootprint(3, 1, 1, 40), "F1"},
+		{"40ft next to the 40ft", newFootprint(4, 1, 1, 40), ""},
+		{"40ft over the upper 20ft", newFootprint(1, 1, 2, 40), "T2"},
+		{"40ft beside the upper 20ft", newFootprint(2, 1, 2, 40), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if occupant := findOccupant(row, tt.f); occupant != nil {
+				got = occupant.ContainerNumber
+			}
+			if got != tt.want {
+				t.Fatalf("findOccupant() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccupancyMapMixedSizes(t *testing.T) {
+	// Two 20fts carry a 40ft; a 40ft stands next to them with a 20ft placed
+	// mid-span on it, as a manual placement could have left it.
+	occupied := newOccupancyMap([]models.Container{
+		box("T1", 20, 1, 1, 1),
+		box("T2", 20, 2, 1, 1),
+		box("F1", 40, 1, 1, 2),
+		box("F2", 40, 3, 1, 1),
+		box("T3", 20, 4, 1, 2),
+	})
+
+	free := []struct {
+		name string
+		f    footprint
+		want bool
+	}{
+		{"20ft under the 40ft", newFootprint(2, 1, 1, 20), false},
+		{"40ft on the 40ft over two 20fts", newFootprint(1, 1, 3, 40), true},
+		{"20ft on the first half of the standing 40ft", newFootprint(3, 1, 2, 20), true},
+		{"20ft on the mid-span 20ft", newFootprint(4, 1, 2, 20), false},
+		{"40ft across both stacks", newFootprint(2, 1, 2, 40), false},
+		{"40ft past the standing 40ft", newFootprint(5, 1, 1, 40), true},
+		{"40ft overlapping the standing 40ft", newFootprint(4, 1, 1, 40), false},
+	}
+	for _, tt := range free {
+		t.Run("isFree "+tt.name, func(t *testing.T) {
+			if got := occupied.isFree(tt.f); got != tt.want {
+				t.Fatalf("isFree() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+
+	above := []struct {
+		name string
+		f    footprint
+		want bool
+	}{
+		{"20ft under the 40ft", newFootprint(1, 1, 1, 20), true},
+		{"standing 40ft under the mid-span 20ft", newFootprint(3, 1, 1, 40), true},
+		{"first half of the standing 40ft", newFootprint(3, 1, 1, 20), false},
+		{"top 40ft", newFootprint(1, 1, 2, 40), false},
+	}
+	for _, tt := range above {
+		t.Run("hasAbove "+tt.name, func(t *testing.T) {
+			if got := occupied.hasAbove(tt.f, 4); got != tt.want {
+				t.Fatalf("hasAbove() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
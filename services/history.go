@@ -0,0 +1,105 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
+)
+
+const (
+	EventSuggested = "SUGGESTED"
+	EventPlaced    = "PLACED"
+	EventMoved     = "MOVED"
+	EventPickedUp  = "PICKED_UP"
+
+	// ReasonSupervisorOverride marks placements made with a supervisor override.
+	ReasonSupervisorOverride = "SUPERVISOR_OVERRIDE"
+)
+
+// containerEvent builds a history entry. from and to are nil where the event
+// has no such position.
+func containerEvent(eventType, containerNumber string, yardID uint, from, to *eventPosition, user, reason string) *models.ContainerEvent {
+	event := &models.ContainerEvent{
+		ContainerNumber: containerNumber,
+		YardID:          yardID,
+		Type:            eventType,
+		User:            user,
+		Reason:          reason,
+		OccurredAt:      time.Now(),
+	}
+	if from != nil {
+		event.FromBlock, event.FromSlot, event.FromRow, event.FromTier = from.block, from.f.StartSlot, from.f.Row, from.f.Tier
+	}
+	if to != nil {
+		event.ToBlock, event.ToSlot, event.ToRow, event.ToTier = to.block, to.f.StartSlot, to.f.Row, to.f.Tier
+	}
+	return event
+}
+
+// eventPosition is a footprint in a named block.
+type eventPosition struct {
+	block string
+	f     footprint
+}
+
+func at(block string, f footprint) *eventPosition {
+	return &eventPosition{block: block, f: f}
+}
+
+func recordEvent(tx repositories.Store, event *models.ContainerEvent) error {
+	if err := tx.Events().Create(event); err != nil {
+		log.Printf("❌ Failed to record %s event for %s: %v", event.Type, event.ContainerNumber, err)
+		return err
+	}
+	return nil
+}
+
+// GetContainerHistory returns every recorded event of a container, oldest
+// first, across all yards.
+func (s *YardService) GetContainerHistory(containerNumber string) (*dto.ContainerHistoryResponse, error) {
+	events, err := s.store.Events().ListByContainer(containerNumber)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, newNotFoundError("container_not_found", "no history recorded for container "+containerNumber)
+	}
+
+	yards, err := s.store.Yards().List()
+	if err != nil {
+		return nil, err
+	}
+	yardNames := make(map[uint]string, len(yards))
+	for _, yard := range yards {
+		yardNames[yard.ID] = yard.Name
+	}
+
+	response := &dto.ContainerHistoryResponse{
+		ContainerNumber: containerNumber,
+		Events:          make([]dto.ContainerEvent, 0, len(events)),
+	}
+	for _, event := range events {
+		response.Events = append(response.Events, eventResponse(event, yardNames[event.YardID]))
+	}
+	return response, nil
+}
+
+func eventResponse(event models.ContainerEvent, yardName string) dto.ContainerEvent {
+	response := dto.ContainerEvent{
+		Type:       event.Type,
+		Yard:       yardName,
+		User:       event.User,
+		Reason:     event.Reason,
+		OccurredAt: event.OccurredAt,
+	}
+	if event.FromBlock != "" {
+		response.From = &dto.Position{Block: event.FromBlock, Slot: event.FromSlot, Row: event.FromRow, Tier: event.FromTier}
+	}
+	if event.ToBlock != "" {
+		response.To = &dto.Position{Block: event.ToBlock, Slot: event.ToSlot, Row: event.ToRow, Tier: event.ToTier}
+	}
+	return response
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
+)
+
+const (
+	CodeContainersAbove = "containers_above"
+	CodeContainerMoved  = "container_moved"
+)
+
+// MoveContainer relocates a placed container within its yard in a single
+// transaction. The container never leaves the yard: its old position is only
+// released to the checks of the new one, and nobody else sees it free.
+func (s *YardService) MoveContainer(req dto.MoveRequest) (*dto.ContainerEvent, error) {
+	var move *models.ContainerEvent
+	var changes occupancyChanges
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		container, err := tx.Containers().FindInYard(req.ContainerNumber, req.Yard)
+		if errors.Is(err, repositories.ErrNotFound) {
+			log.Printf("❌ Container not found: Number=%s, Yard=%s", req.ContainerNumber, req.Yard)
+			return newNotFoundError("container_not_found", "container not found in specified yard")
+		}
+		if err != nil {
+			return err
+		}
+		if !container.IsPlaced {
+			return newConflictError("container_not_placed", "container is not currently placed")
+		}
+
+		target, err := findBlockByName(tx, req.Yard, req.Block)
+		if err != nil {
+			return err
+		}
+
+		source := container.BlockID
+		if err := tx.Blocks().Lock(source, target.ID); err != nil {
+			return err
+		}
+		// Read the container again under the locks in case it moved or was
+		// picked up meanwhile.
+		if container, err = tx.Containers().FindInYard(req.ContainerNumber, req.Yard); err != nil {
+			return err
+		}
+		if !container.IsPlaced {
+			return newConflictError("container_not_placed", "container is not currently placed")
+		}
+		if container.BlockID != source {
+			return newConflictError(CodeContainerMoved, "container changed block while the move waited for its lock; retry the move")
+		}
+
+		to := footprint{StartSlot: req.Slot, Span: containerFootprint(*container).Span, Row: req.Row, Tier: req.Tier}
+		move, err = moveContainer(tx, container, *target, to, req.Reason, req.User, req.SupervisorOverride, &changes)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.applyOccupancy(changes)
+	response := eventResponse(*move, req.Yard)
+	return &response, nil
+}
+
+// moveContainer relocates a placed container to footprint to in block target
+// and records the move in its history and in changes. The caller holds the
+// locks of both blocks.
+func moveContainer(tx repositories.Store, container *models.Container, target models.Block, to footprint, reason, user string, override bool, changes *occupancyChanges) (*models.ContainerEvent, error) {
+	from := containerFootprint(*container)
+	if container.BlockID == target.ID && from == to {
+		return nil, newInvalidError("same_position", "container is already at that position")
+	}
+
+	above, err := containersAbove(tx, container.BlockID, from)
+	if err != nil {
+		return nil, err
+	}
+	if len(above) > 0 {
+		return nil, &ServiceError{
+			Kind: KindRuleViolation,
+			Code: CodeContainersAbove,
+			Message: fmt.Sprintf("container %s has %d container(s) stacked on it, starting with %s",
+				container.ContainerNumber, len(above), above[0].ContainerNumber),
+		}
+	}
+
+	// Release the old position inside the transaction so the checks of the
+	// new one neither see the container as an occupant nor as a support.
+	container.IsPlaced = false
+	if err := tx.Containers().Save(container); err != nil {
+		return nil, err
+	}
+
+	attrs := containerAttributes{
+		Size:        container.ContainerSize,
+		Height:      container.ContainerHeight,
+		Type:        container.ContainerType,
+		SizeType:    container.SizeType,
+		OutOfGauge:  container.OutOfGauge,
+		GrossWeight: container.GrossWeight,
+
+		ExpectedDeparture: container.ExpectedDeparture,
+		VesselVoyage:      container.VesselVoyage,
+	}
+	if err := checkPosition(tx, target, to, attrs, container.ContainerNumber, override); err != nil {
+		return nil, err
+	}
+
+	source, err := tx.Blocks().FindByID(container.BlockID)
+	if err != nil {
+		return nil, err
+	}
+	move := containerEvent(EventMoved, container.ContainerNumber, target.YardID,
+		at(source.Name, from), at(target.Name, to), user, reason)
+
+	container.BlockID = target.ID
+	container.Slot = to.StartSlot
+	container.SlotSpan = to.Span
+	container.Row = to.Row
+	container.Tier = to.Tier
+	container.IsPlaced = true
+	if err := tx.Containers().Save(container); err != nil {
+		log.Printf("❌ Failed to move container: %v", err)
+		return nil, placementWriteError(err)
+	}
+	if err := recordEvent(tx, move); err != nil {
+		return nil, err
+	}
+	changes.moved(*source, from, target, *container)
+
+	log.Printf("🚚 Container moved (%s): %s from Block=%s Slot=%d-%d Row=%d Tier=%d to Block=%s Slot=%d-%d Row=%d Tier=%d",
+		reason, container.ContainerNumber, source.Name, from.StartSlot, from.EndSlot(), from.Row, from.Tier,
+		target.Name, to.StartSlot, to.EndSlot(), to.Row, to.Tier)
+	return move, nil
+}
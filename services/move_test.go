@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/repositories"
+)
+
+func TestMoveReadsContainerAgainUnderLocks(t *testing.T) {
+	tests := []struct {
+		name      string
+		meanwhile func(t *testing.T, tx repositories.Store)
+		wantCode  string
+	}{
+		{"moved to another block", func(t *testing.T, tx repositories.Store) {
+			other, err := tx.Blocks().FindByName("YRD1", "LC02")
+			if err != nil {
+				t.Fatalf("FindByName: %v", err)
+			}
+			container, _ := tx.Containers().FindByNumber("CONT0001")
+			container.BlockID = other.ID
+			if err := tx.Containers().Save(container); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+		}, CodeContainerMoved},
+		{"picked up", func(t *testing.T, tx repositories.Store) {
+			container, _ := tx.Containers().FindByNumber("CONT0001")
+			container.IsPlaced = false
+			if err := tx.Containers().Save(container); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+		}, "container_not_placed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forEachStore(t, func(t *testing.T, store repositories.Store) {
+				place(t, newTestYard(t, store, NewRedisService(nil)), "CONT0001", 1, 1, 1)
+				management := NewYardManagementService(store, NewRedisService(nil))
+				if _, err := management.CreateBlock("YRD1", dto.BlockRequest{Name: "LC02", MaxSlot: 10, MaxRow: 5, MaxTier: 4}); err != nil {
+					t.Fatalf("CreateBlock: %v", err)
+				}
+
+				// The change lands just before the move takes its locks.
+				hooked := newHookedStore(store)
+				hooked.hooks.beforeLock = func(tx repositories.Store) {
+					hooked.hooks.beforeLock = nil
+					tt.meanwhile(t, tx)
+				}
+				s := NewYardService(hooked, NewRedisService(nil))
+				_, err := s.MoveContainer(dto.MoveRequest{
+					Yard: "YRD1", ContainerNumber: "CONT0001", Block: "LC01", Slot: 2, Row: 1, Tier: 1, Reason: "HOUSEKEEPING",
+				})
+				wantCode(t, err, tt.wantCode)
+			})
+		})
+	}
+}
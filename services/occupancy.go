@@ -0,0 +1,303 @@
+package services
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"time"
+
+	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
+)
+
+// The cached occupancy of a block is a bitmap of planes with one bit per cell
+// and a height map with one byte per cell. Cells are numbered slot first, then
+// row, then tier: cell ((tier-1)*MaxRow + row-1)*MaxSlot + slot-1. The bit of
+// a cell in plane p is p*cells + cell, bit 0 being the most significant bit of
+// the first byte as with Redis SETBIT. Besides the height, the planes hold all
+// the stacking rules need to know about the container on a cell.
+const (
+	planeOccupied    = iota // cells covered by a container
+	planeStart              // first cell of every container
+	planeClosesStack        // cells of open-top, flat rack and out-of-gauge containers
+	planeShort              // cells of 10ft containers
+	occupancyPlanes
+)
+
+func blockCells(block models.Block) int {
+	return block.MaxSlot * block.MaxRow * block.MaxTier
+}
+
+func cellIndex(block models.Block, slot, row, tier int) int64 {
+	return int64(((tier-1)*block.MaxRow+(row-1))*block.MaxSlot + (slot - 1))
+}
+
+func planeBit(block models.Block, plane int, cell int64) int64 {
+	return int64(plane*blockCells(block)) + cell
+}
+
+func footprintCells(block models.Block, f footprint) []int64 {
+	cells := make([]int64, 0, f.Span)
+	for slot := f.StartSlot; slot <= f.EndSlot(); slot++ {
+		cells = append(cells, cellIndex(block, slot, f.Row, f.Tier))
+	}
+	return cells
+}
+
+// footprintBits returns the bits of every plane for the cells of f.
+func footprintBits(block models.Block, f footprint) []int64 {
+	var bits []int64
+	for _, cell := range footprintCells(block, f) {
+		for plane := 0; plane < occupancyPlanes; plane++ {
+			bits = append(bits, planeBit(block, plane, cell))
+		}
+	}
+	return bits
+}
+
+// containerBits returns the bits a placed container sets.
+func containerBits(block models.Block, container models.Container) []int64 {
+	var bits []int64
+	for i, cell := range footprintCells(block, containerFootprint(container)) {
+		bits = append(bits, planeBit(block, planeOccupied, cell))
+		if i == 0 {
+			bits = append(bits, planeBit(block, planeStart, cell))
+		}
+		if closesStack(container.ContainerType, container.OutOfGauge) {
+			bits = append(bits, planeBit(block, planeClosesStack, cell))
+		}
+		if container.ContainerSize == 10 {
+			bits = append(bits, planeBit(block, planeShort, cell))
+		}
+	}
+	return bits
+}
+
+// cellHeights returns the height map entries of the cells of f, in tenths of
+// a foot; a height of 0 marks the cells free.
+func cellHeights(block models.Block, f footprint, height float64) []cellHeight {
+	tenths := byte(min(math.Round(height*10), math.MaxUint8))
+	heights := make([]cellHeight, 0, f.Span)
+	for _, cell := range footprintCells(block, f) {
+		heights = append(heights, cellHeight{Cell: cell, Tenths: tenths})
+	}
+	return heights
+}
+
+// encodeOccupancy builds the bitmap and height map of a block's containers.
+func encodeOccupancy(block models.Block, containers []models.Container) (bitmap, heights []byte) {
+	bitmap = make([]byte, (occupancyPlanes*blockCells(block)+7)/8)
+	heights = make([]byte, blockCells(block))
+	for _, container := range containers {
+		for _, bit := range containerBits(block, container) {
+			if int(bit/8) < len(bitmap) {
+				bitmap[bit/8] |= 0x80 >> (bit % 8)
+			}
+		}
+		for _, h := range cellHeights(block, containerFootprint(container), container.ContainerHeight) {
+			if int(h.Cell) < len(heights) {
+				heights[h.Cell] = h.Tenths
+			}
+		}
+	}
+	return bitmap, heights
+}
+
+// decodeOccupancy reads the occupied cells of a block back from its bitmap and
+// height map, along with stand-ins for its containers that carry what the
+// stacking rules read: footprint, 10ft or not, height and whether they close
+// their stack. It reports false when the maps do not fit the block.
+func decodeOccupancy(block models.Block, bitmap, heights []byte) (occupancyMap, []models.Container, bool) {
+	cells := blockCells(block)
+	if len(bitmap) < (occupancyPlanes*cells+7)/8 || len(heights) < cells {
+		return nil, nil, false
+	}
+	isSet := func(plane int, cell int64) bool {
+		bit := planeBit(block, plane, cell)
+		return bitmap[bit/8]&(0x80>>(bit%8)) != 0
+	}
+
+	occupied := make(occupancyMap)
+	var containers []models.Container
+	for tier := 1; tier <= block.MaxTier; tier++ {
+		for row := 1; row <= block.MaxRow; row++ {
+			for slot := 1; slot <= block.MaxSlot; slot++ {
+				cell := cellIndex(block, slot, row, tier)
+				if !isSet(planeOccupied, cell) {
+					continue
+				}
+				occupied[getPositionKey(slot, row, tier)] = true
+				if !isSet(planeStart, cell) {
+					continue
+				}
+
+				// A container runs on into the next slot unless another one
+				// starts there.
+				span := 1
+				if next := cell + 1; slot < block.MaxSlot && isSet(planeOccupied, next) && !isSet(planeStart, next) {
+					span = 2
+				}
+				size := 20
+				if isSet(planeShort, cell) {
+					size = 10
+				} else if span == 2 {
+					size = 40
+				}
+				containers = append(containers, models.Container{
+					BlockID:         block.ID,
+					ContainerSize:   size,
+					ContainerHeight: float64(heights[cell]) / 10,
+					OutOfGauge:      isSet(planeClosesStack, cell), // stands in for any container closing its stack
+					Slot:            slot,
+					SlotSpan:        span,
+					Row:             row,
+					Tier:            tier,
+					IsPlaced:        true,
+				})
+			}
+		}
+	}
+	return occupied, containers, true
+}
+
+// rankableFromCache reports whether positions of plan can be ranked for attrs
+// from the cached occupancy, which knows how containers stack but not their
+// weights or departures.
+func rankableFromCache(plan models.YardPlan, attrs containerAttributes) bool {
+	return attrs.ExpectedDeparture == nil && !(plan.StackByWeight && attrs.GrossWeight > 0)
+}
+
+// blockOccupancy returns the occupied cells and placed containers of a block.
+// With fromCache the cached maps are read in one round trip and the
+// containers are the stand-ins decoded from them; on a miss, or without
+// fromCache, they are loaded from the database, and a missing block is cached
+// again.
+func (s *YardService) blockOccupancy(tx repositories.Store, block models.Block, fromCache bool) (occupancyMap, []models.Container, error) {
+	if !fromCache {
+		placed, err := tx.Containers().ListPlaced(block.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return newOccupancyMap(placed), placed, nil
+	}
+
+	cached, cacheErr := s.cache.GetBlockOccupancy(block.ID)
+	if cacheErr == nil && cached.Found {
+		if occupied, containers, ok := decodeOccupancy(block, cached.Bitmap, cached.Heights); ok {
+			return occupied, containers, nil
+		}
+		log.Printf("⚠️ Cached occupancy of block %d does not fit its dimensions", block.ID)
+	}
+
+	placed, err := tx.Containers().ListPlaced(block.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cacheErr == nil {
+		bitmap, heights := encodeOccupancy(block, placed)
+		s.cache.SetBlockOccupancy(block.ID, cached.Version, bitmap, heights)
+	}
+	return newOccupancyMap(placed), placed, nil
+}
+
+// occupancyChange is a committed change of where a container stands: from is
+// nil when it arrived in the yard, to is nil when it left.
+type occupancyChange struct {
+	container models.Container
+	from, to  *blockFootprint
+}
+
+type blockFootprint struct {
+	block models.Block
+	f     footprint
+}
+
+// occupancyChanges collects the changes of a transaction, to be applied to
+// the cached maps once it committed.
+type occupancyChanges []occupancyChange
+
+func (c *occupancyChanges) placed(block models.Block, container models.Container) {
+	*c = append(*c, occupancyChange{container: container, to: &blockFootprint{block, containerFootprint(container)}})
+}
+
+func (c *occupancyChanges) removed(block models.Block, container models.Container, from footprint) {
+	*c = append(*c, occupancyChange{container: container, from: &blockFootprint{block, from}})
+}
+
+func (c *occupancyChanges) moved(source models.Block, from footprint, target models.Block, container models.Container) {
+	*c = append(*c, occupancyChange{container: container,
+		from: &blockFootprint{source, from}, to: &blockFootprint{target, containerFootprint(container)}})
+}
+
+// applyOccupancy updates the cached maps of the changed blocks. Bumping their
+// versions also retires the suggestions cached for them. It runs after the
+// transaction commits, so the cache is only eventually consistent with the
+// database: until the update lands, or the reconciler repairs a failed one,
+// suggestions may rank from stale occupancy. That is why a suggestion checks
+// the position it picks against the database, and a placement checks its
+// position under the block lock.
+func (s *YardService) applyOccupancy(changes occupancyChanges) {
+	for _, change := range changes {
+		if change.from != nil && (change.to == nil || change.to.block.ID != change.from.block.ID) {
+			s.cache.UpdateBlockOccupancy(change.from.block.ID, footprintBits(change.from.block, change.from.f), nil,
+				cellHeights(change.from.block, change.from.f, 0))
+		}
+		if change.to != nil {
+			var clear []int64
+			var heights []cellHeight
+			if change.from != nil && change.from.block.ID == change.to.block.ID {
+				clear = footprintBits(change.from.block, change.from.f)
+				heights = cellHeights(change.from.block, change.from.f, 0)
+			}
+			heights = append(heights, cellHeights(change.to.block, change.to.f, change.container.ContainerHeight)...)
+			s.cache.UpdateBlockOccupancy(change.to.block.ID, clear, containerBits(change.to.block, change.container), heights)
+		}
+	}
+}
+
+// ReconcileOccupancy rebuilds the cached maps of every block from the
+// database, logging blocks whose cached occupancy had drifted.
+func (s *YardService) ReconcileOccupancy() error {
+	blocks, err := s.store.Blocks().List()
+	if err != nil {
+		return err
+	}
+
+	for _, block := range blocks {
+		cached, err := s.cache.GetBlockOccupancy(block.ID)
+		if err != nil {
+			return err
+		}
+		placed, err := s.store.Containers().ListPlaced(block.ID)
+		if err != nil {
+			return err
+		}
+
+		bitmap, heights := encodeOccupancy(block, placed)
+		if cached.Found && (!sameBytes(cached.Bitmap, bitmap) || !sameBytes(cached.Heights, heights)) {
+			log.Printf("🔧 Repairing drifted occupancy of block %s (ID: %d)", block.Name, block.ID)
+		}
+		s.cache.SetBlockOccupancy(block.ID, cached.Version, bitmap, heights)
+	}
+	return nil
+}
+
+// sameBytes compares cached maps, which SETBIT and SETRANGE may have padded
+// with zeros.
+func sameBytes(a, b []byte) bool {
+	return bytes.Equal(bytes.TrimRight(a, "\x00"), bytes.TrimRight(b, "\x00"))
+}
+
+// RunOccupancyReconciler reconciles the cached occupancy every interval until
+// the process exits.
+func (s *YardService) RunOccupancyReconciler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.ReconcileOccupancy(); err != nil {
+			log.Printf("⚠️ Occupancy reconciliation skipped: %v", err)
+		}
+	}
+}
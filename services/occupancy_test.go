@@ -0,0 +1,70 @@
+package services
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"backend_yard_planning_system/models"
+)
+
+func TestOccupancyMapsRoundTrip(t *testing.T) {
+	block := models.Block{ID: 1, Name: "LC01", MaxSlot: 4, MaxRow: 2, MaxTier: 3}
+
+	openTop := box("OPEN1", 20, 1, 1, 2)
+	openTop.ContainerType = ContainerTypeOpenTop
+	highCube := box("HIGH1", 40, 2, 1, 1)
+	highCube.ContainerHeight = 9.6
+	outOfGauge := box("OOG1", 40, 2, 1, 2)
+	outOfGauge.OutOfGauge = true
+	containers := []models.Container{
+		box("T1", 20, 1, 1, 1), highCube, box("T2", 20, 4, 1, 1), openTop, outOfGauge,
+		// Two 40fts side by side are told apart by where the second one starts.
+		box("F1", 40, 1, 2, 1), box("F2", 40, 3, 2, 1), box("TEN1", 10, 4, 2, 2),
+	}
+
+	bitmap, heights := encodeOccupancy(block, containers)
+	occupied, decoded, ok := decodeOccupancy(block, bitmap, heights)
+	if !ok {
+		t.Fatal("decodeOccupancy rejected maps it encoded")
+	}
+	if want := newOccupancyMap(containers); !reflect.DeepEqual(occupied, want) {
+		t.Fatalf("decoded %d occupied cells, want %d", len(occupied), len(want))
+	}
+
+	// The stand-ins keep what the stacking rules read.
+	type stacked struct {
+		f      footprint
+		size   int
+		height float64
+		closes bool
+	}
+	project := func(containers []models.Container) []stacked {
+		var out []stacked
+		for _, c := range containers {
+			size := c.ContainerSize
+			if size != 10 {
+				size = 20 * slotSpanForSize(size)
+			}
+			out = append(out, stacked{containerFootprint(c), size, c.ContainerHeight, closesStack(c.ContainerType, c.OutOfGauge)})
+		}
+		sort.Slice(out, func(i, j int) bool {
+			a, b := out[i].f, out[j].f
+			if a.Tier != b.Tier {
+				return a.Tier < b.Tier
+			}
+			if a.Row != b.Row {
+				return a.Row < b.Row
+			}
+			return a.StartSlot < b.StartSlot
+		})
+		return out
+	}
+	if got, want := project(decoded), project(containers); !reflect.DeepEqual(got, want) {
+		t.Fatalf("decoded containers\n%+v\nwant\n%+v", got, want)
+	}
+
+	if _, _, ok := decodeOccupancy(models.Block{MaxSlot: 5, MaxRow: 2, MaxTier: 3}, bitmap, heights); ok {
+		t.Fatal("decodeOccupancy accepted maps of a smaller block")
+	}
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"backend_yard_planning_system/models"
+)
+
+// PlanArea is the part of a block a yard plan covers, with the block's tier
+// limit.
+type PlanArea struct {
+	StartSlot int
+	EndSlot   int
+	StartRow  int
+	EndRow    int
+	MaxTier   int
+}
+
+// StackHeights reports how many tiers are currently stacked on a ground
+// position, i.e. the highest occupied tier (0 when empty).
+type StackHeights interface {
+	StackHeight(slot, row int) int
+}
+
+// StackView is the occupancy passed to scored strategies: stack heights plus
+// the containers stacked under a position.
+type StackView interface {
+	StackHeights
+	// Below returns the placed containers at tiers lower than tier whose
+	// footprint covers (slot, row).
+	Below(slot, row, tier int) []models.Container
+}
+
+// IncomingContainer describes the container a position is being suggested for.
+type IncomingContainer struct {
+	Size              int
+	SlotSpan          int
+	GrossWeight       float64
+	ExpectedDeparture *time.Time
+	VesselVoyage      string
+}
+
+// Candidate is a position a strategy proposes; the suggestion engine still
+// checks occupancy, stacking rules and the container footprint.
+type Candidate struct {
+	Slot int
+	Row  int
+	Tier int
+}
+
+// PlacementStrategy orders the candidate positions of a yard plan. A plan
+// selects its strategy by name through PriorityDirection.
+type PlacementStrategy interface {
+	Name() string
+	Candidates(area PlanArea, stacks StackHeights) []Candidate
+}
+
+// ScoredPlacementStrategy also prices each valid candidate. The engine picks
+// the cheapest one, using the candidate order to break ties.
+type ScoredPlacementStrategy interface {
+	PlacementStrategy
+	Cost(candidate Candidate, stacks StackView, incoming IncomingContainer) float64
+}
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = make(map[string]PlacementStrategy)
+)
+
+// RegisterPlacementStrategy makes a strategy selectable by yard plans. It fails
+// if a strategy with the same name is already registered.
+func RegisterPlacementStrategy(strategy PlacementStrategy) error {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+
+	name := strategy.Name()
+	if _, exists := strategies[name]; exists {
+		return fmt.Errorf("placement strategy %s is already registered", name)
+	}
+	strategies[name] = strategy
+	return nil
+}
+
+func LookupPlacementStrategy(name string) (PlacementStrategy, bool) {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+
+	strategy, ok := strategies[name]
+	return strategy, ok
+}
+
+// PlacementStrategyNames lists the registered strategies in name order.
+func PlacementStrategyNames() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	for _, strategy := range []PlacementStrategy{
+		leftToRightStrategy{},
+		rightToLeftStrategy{},
+		bottomToTopStrategy{},
+		topToBottomStrategy{},
+		fillStackFirstStrategy{},
+		spreadEvenlyStrategy{},
+		minRehandleStrategy{},
+	} {
+		if err := RegisterPlacementStrategy(strategy); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// leftToRightStrategy fills a whole tier, row by row from the lowest slot,
+// before moving up a tier.
+type leftToRightStrategy struct{}
+
+func (leftToRightStrategy) Name() string { return "LEFT_TO_RIGHT" }
+
+func (leftToRightStrategy) Candidates(area PlanArea, _ StackHeights) []Candidate {
+	var candidates []Candidate
+	for tier := 1; tier <= area.MaxTier; tier++ {
+		for row := area.StartRow; row <= area.EndRow; row++ {
+			for slot := area.StartSlot; slot <= area.EndSlot; slot++ {
+				candidates = append(candidates, Candidate{Slot: slot, Row: row, Tier: tier})
+			}
+		}
+	}
+	return candidates
+}
+
+// rightToLeftStrategy is leftToRightStrategy starting from the highest slot.
+type rightToLeftStrategy struct{}
+
+func (rightToLeftStrategy) Name() string { return "RIGHT_TO_LEFT" }
+
+func (rightToLeftStrategy) Candidates(area PlanArea, _ StackHeights) []Candidate {
+	var candidates []Candidate
+	for tier := 1; tier <= area.MaxTier; tier++ {
+		for row := area.StartRow; row <= area.EndRow; row++ {
+			for slot := area.EndSlot; slot >= area.StartSlot; slot-- {
+				candidates = append(candidates, Candidate{Slot: slot, Row: row, Tier: tier})
+			}
+		}
+	}
+	return candidates
+}
+
+// bottomToTopStrategy builds each stack to full height, slot by slot.
+type bottomToTopStrategy struct{}
+
+func (bottomToTopStrategy) Name() string { return "BOTTOM_TO_TOP" }
+
+func (bottomToTopStrategy) Candidates(area PlanArea, _ StackHeights) []Candidate {
+	var candidates []Candidate
+	for slot := area.StartSlot; slot <= area.EndSlot; slot++ {
+		for row := area.StartRow; row <= area.EndRow; row++ {
+			for tier := 1; tier <= area.MaxTier; tier++ {
+				candidates = append(candidates, Candidate{Slot: slot, Row: row, Tier: tier})
+			}
+		}
+	}
+	return candidates
+}
+
+// topToBottomStrategy works through the plan row by row from the top row
+// (the highest row number) down, building each stack of the row to full
+// height.
+type topToBottomStrategy struct{}
+
+func (topToBottomStrategy) Name() string { return "TOP_TO_BOTTOM" }
+
+func (topToBottomStrategy) Candidates(area PlanArea, _ StackHeights) []Candidate {
+	var candidates []Candidate
+	for row := area.EndRow; row >= area.StartRow; row-- {
+		for slot := area.StartSlot; slot <= area.EndSlot; slot++ {
+			for tier := 1; tier <= area.MaxTier; tier++ {
+				candidates = append(candidates, Candidate{Slot: slot, Row: row, Tier: tier})
+			}
+		}
+	}
+	return candidates
+}
+
+// fillStackFirstStrategy tops up the highest stacks that still have room,
+// keeping ground positions free for as long as possible.
+type fillStackFirstStrategy struct{}
+
+func (fillStackFirstStrategy) Name() string { return "FILL_STACK_FIRST" }
+
+func (fillStackFirstStrategy) Candidates(area PlanArea, stacks StackHeights) []Candidate {
+	return stackTops(area, stacks, func(a, b int) bool { return a > b })
+}
+
+// spreadEvenlyStrategy places on the lowest stacks first so all stacks in the
+// plan grow at the same rate.
+type spreadEvenlyStrategy struct{}
+
+func (spreadEvenlyStrategy) Name() string { return "SPREAD_EVENLY" }
+
+func (spreadEvenlyStrategy) Candidates(area PlanArea, stacks StackHeights) []Candidate {
+	return stackTops(area, stacks, func(a, b int) bool { return a < b })
+}
+
+// stackTops returns the next free tier of every ground position that is not
+// full, ordered by stack height with less, ties broken by slot then row.
+func stackTops(area PlanArea, stacks StackHeights, less func(a, b int) bool) []Candidate {
+	var candidates []Candidate
+	for slot := area.StartSlot; slot <= area.EndSlot; slot++ {
+		for row := area.StartRow; row <= area.EndRow; row++ {
+			if height := stacks.StackHeight(slot, row); height < area.MaxTier {
+				candidates = append(candidates, Candidate{Slot: slot, Row: row, Tier: height + 1})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return less(candidates[i].Tier, candidates[j].Tier)
+	})
+	return candidates
+}
+
+// minRehandleStrategy scans like LEFT_TO_RIGHT but picks the position that
+// causes the fewest future rehandles: every container below the candidate
+// that departs before the incoming one will need the incoming one moved off
+// it first. Containers without an expected departure are not counted.
+type minRehandleStrategy struct{}
+
+func (minRehandleStrategy) Name() string { return "MIN_REHANDLE" }
+
+func (minRehandleStrategy) Candidates(area PlanArea, stacks StackHeights) []Candidate {
+	return leftToRightStrategy{}.Candidates(area, stacks)
+}
+
+func (minRehandleStrategy) Cost(candidate Candidate, stacks StackView, incoming IncomingContainer) float64 {
+	return float64(countRehandles(candidate, stacks, incoming))
+}
+
+func countRehandles(candidate Candidate, stacks StackView, incoming IncomingContainer) int {
+	if incoming.ExpectedDeparture == nil {
+		return 0
+	}
+
+	span := incoming.SlotSpan
+	if span < 1 {
+		span = 1
+	}
+
+	seen := make(map[string]bool)
+	rehandles := 0
+	for slot := candidate.Slot; slot < candidate.Slot+span; slot++ {
+		for _, below := range stacks.Below(slot, candidate.Row, candidate.Tier) {
+			if seen[below.ContainerNumber] {
+				continue
+			}
+			seen[below.ContainerNumber] = true
+
+			if below.ExpectedDeparture != nil && below.ExpectedDeparture.Before(*incoming.ExpectedDeparture) {
+				rehandles++
+			}
+		}
+	}
+	return rehandles
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"backend_yard_planning_system/models"
+)
+
+// fakeStacks is a StackHeights keyed by ground position.
+type fakeStacks map[[2]int]int
+
+func (f fakeStacks) StackHeight(slot, row int) int {
+	return f[[2]int{slot, row}]
+}
+
+func TestPlacementStrategyCandidates(t *testing.T) {
+	area := PlanArea{StartSlot: 1, EndSlot: 2, StartRow: 1, EndRow: 2, MaxTier: 2}
+	// Slot 1 row 1 is full, slot 1 row 2 is empty and slot 2 holds one tier.
+	stacks := fakeStacks{{1, 1}: 2, {2, 1}: 1, {2, 2}: 1}
+
+	leftToRight := []Candidate{
+		{1, 1, 1}, {2, 1, 1}, {1, 2, 1}, {2, 2, 1},
+		{1, 1, 2}, {2, 1, 2}, {1, 2, 2}, {2, 2, 2},
+	}
+	tests := map[string][]Candidate{
+		"LEFT_TO_RIGHT": leftToRight,
+		"RIGHT_TO_LEFT": {
+			{2, 1, 1}, {1, 1, 1}, {2, 2, 1}, {1, 2, 1},
+			{2, 1, 2}, {1, 1, 2}, {2, 2, 2}, {1, 2, 2},
+		},
+		"BOTTOM_TO_TOP": {
+			{1, 1, 1}, {1, 1, 2}, {1, 2, 1}, {1, 2, 2},
+			{2, 1, 1}, {2, 1, 2}, {2, 2, 1}, {2, 2, 2},
+		},
+		"TOP_TO_BOTTOM": {
+			{1, 2, 1}, {1, 2, 2}, {2, 2, 1}, {2, 2, 2},
+			{1, 1, 1}, {1, 1, 2}, {2, 1, 1}, {2, 1, 2},
+		},
+		"FILL_STACK_FIRST": {{2, 1, 2}, {2, 2, 2}, {1, 2, 1}},
+		"SPREAD_EVENLY":    {{1, 2, 1}, {2, 1, 2}, {2, 2, 2}},
+		"MIN_REHANDLE":     leftToRight,
+	}
+
+	if names := PlacementStrategyNames(); len(names) != len(tests) {
+		t.Fatalf("registered strategies %v, tested %d", names, len(tests))
+	}
+	for _, name := range PlacementStrategyNames() {
+		t.Run(name, func(t *testing.T) {
+			want, ok := tests[name]
+			if !ok {
+				t.Fatalf("strategy %s has no test case", name)
+			}
+			strategy, _ := LookupPlacementStrategy(name)
+			if got := strategy.Candidates(area, stacks); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Candidates() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMinRehandleCost(t *testing.T) {
+	early := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := early.Add(48 * time.Hour)
+
+	lower := box("LOW1", 20, 1, 1, 1)
+	lower.ExpectedDeparture = &early
+	upper := box("UPP1", 20, 1, 1, 2)
+	upper.ExpectedDeparture = &late
+	stacks := newBlockStacks([]models.Container{lower, upper, box("UNK1", 20, 2, 1, 1)}, 4)
+
+	strategy, _ := LookupPlacementStrategy("MIN_REHANDLE")
+	scored := strategy.(ScoredPlacementStrategy)
+
+	tests := []struct {
+		name      string
+		candidate Candidate
+		incoming  IncomingContainer
+		want      float64
+	}{
+		{"no departure given", Candidate{1, 1, 3}, IncomingContainer{Size: 20, SlotSpan: 1}, 0},
+		{"leaves after both below", Candidate{1, 1, 3}, IncomingContainer{Size: 20, SlotSpan: 1, ExpectedDeparture: ptrTime(late.Add(time.Hour))}, 2},
+		{"leaves between them", Candidate{1, 1, 3}, IncomingContainer{Size: 20, SlotSpan: 1, ExpectedDeparture: ptrTime(early.Add(time.Hour))}, 1},
+		{"leaves first", Candidate{1, 1, 3}, IncomingContainer{Size: 20, SlotSpan: 1, ExpectedDeparture: ptrTime(early.Add(-time.Hour))}, 0},
+		{"40ft over a departure-less box", Candidate{1, 1, 2}, IncomingContainer{Size: 40, SlotSpan: 2, ExpectedDeparture: ptrTime(late)}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scored.Cost(tt.candidate, stacks, tt.incoming); got != tt.want {
+				t.Fatalf("Cost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
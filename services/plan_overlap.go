@@ -0,0 +1,94 @@
+package services
+
+import (
+	"fmt"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
+)
+
+// plansOverlap reports whether two plans in the same block share at least one
+// slot/row cell.
+func plansOverlap(a, b models.YardPlan) bool {
+	return a.BlockID == b.BlockID &&
+		a.StartSlot <= b.EndSlot && b.StartSlot <= a.EndSlot &&
+		a.StartRow <= b.EndRow && b.StartRow <= a.EndRow
+}
+
+func planCovers(plan models.YardPlan, slot, row int) bool {
+	return slot >= plan.StartSlot && slot <= plan.EndSlot &&
+		row >= plan.StartRow && row <= plan.EndRow
+}
+
+// overlapAllowed reports whether two overlapping plans may coexist. Both must
+// opt in and their priorities must differ so precedence is never ambiguous.
+func overlapAllowed(a, b models.YardPlan) bool {
+	return a.AllowOverlap && b.AllowOverlap && a.Priority != b.Priority
+}
+
+// checkPlanOverlap rejects a plan that overlaps another plan in its block,
+// unless both plans allow overlapping with distinct priorities.
+func checkPlanOverlap(tx repositories.Store, plan models.YardPlan, blockName string) error {
+	others, err := tx.Plans().ListByBlock(plan.BlockID)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range others {
+		if other.ID == plan.ID || !plansOverlap(plan, other) || overlapAllowed(plan, other) {
+			continue
+		}
+
+		message := fmt.Sprintf("yard plan overlaps plan %d (slots %d-%d, rows %d-%d) in block %s",
+			other.ID, other.StartSlot, other.EndSlot, other.StartRow, other.EndRow, blockName)
+		switch {
+		case plan.AllowOverlap && !other.AllowOverlap:
+			message += "; the existing plan does not allow overlap"
+		case plan.AllowOverlap && other.Priority == plan.Priority:
+			message += fmt.Sprintf("; overlapping plans need distinct priorities (both are %d)", plan.Priority)
+		}
+
+		return &ServiceError{
+			Kind:    KindConflict,
+			Code:    "plan_overlap",
+			Message: message,
+			Details: dto.PlanConflict{
+				PlanID:       other.ID,
+				Block:        blockName,
+				StartSlot:    other.StartSlot,
+				EndSlot:      other.EndSlot,
+				StartRow:     other.StartRow,
+				EndRow:       other.EndRow,
+				Priority:     other.Priority,
+				AllowOverlap: other.AllowOverlap,
+			},
+		}
+	}
+
+	return nil
+}
+
+// shadowingPlans returns the plans that take precedence over plan for some of
+// its cells: overlapping plans in the same block with a higher priority.
+func shadowingPlans(plan models.YardPlan, blockPlans []models.YardPlan) []models.YardPlan {
+	var shadows []models.YardPlan
+	for _, other := range blockPlans {
+		if other.ID == plan.ID || !other.AllowOverlap || other.Priority <= plan.Priority {
+			continue
+		}
+		if plansOverlap(plan, other) {
+			shadows = append(shadows, other)
+		}
+	}
+	return shadows
+}
+
+func isShadowed(shadows []models.YardPlan, slot, row int) bool {
+	for _, shadow := range shadows {
+		if planCovers(shadow, slot, row) {
+			return true
+		}
+	}
+	return false
+}
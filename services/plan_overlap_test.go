@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/repositories"
+)
+
+// TestPlanOverlap creates a second plan next to or over the test plan (slots
+// 1-3, rows 1-5 of LC01).
+func TestPlanOverlap(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing func(*dto.YardPlanRequest) // changes made to the test plan first
+		plan     func(*dto.YardPlanRequest)
+		wantErr  bool
+	}{
+		{
+			name: "adjacent slots",
+			plan: func(r *dto.YardPlanRequest) { r.StartSlot, r.EndSlot = 4, 6 },
+		},
+		{
+			name:    "shared slot",
+			plan:    func(r *dto.YardPlanRequest) { r.StartSlot, r.EndSlot = 3, 6 },
+			wantErr: true,
+		},
+		{
+			name:    "inside the plan",
+			plan:    func(r *dto.YardPlanRequest) { r.StartSlot, r.EndSlot, r.StartRow, r.EndRow = 2, 2, 3, 3 },
+			wantErr: true,
+		},
+		{
+			name: "only the new plan allows overlap",
+			plan: func(r *dto.YardPlanRequest) {
+				r.StartSlot, r.EndSlot, r.AllowOverlap, r.Priority = 2, 4, true, 1
+			},
+			wantErr: true,
+		},
+		{
+			name:     "both allow overlap with equal priorities",
+			existing: func(r *dto.YardPlanRequest) { r.AllowOverlap = true },
+			plan:     func(r *dto.YardPlanRequest) { r.StartSlot, r.EndSlot, r.AllowOverlap = 2, 4, true },
+			wantErr:  true,
+		},
+		{
+			name:     "both allow overlap with distinct priorities",
+			existing: func(r *dto.YardPlanRequest) { r.AllowOverlap = true },
+			plan: func(r *dto.YardPlanRequest) {
+				r.StartSlot, r.EndSlot, r.AllowOverlap, r.Priority = 2, 4, true, 1
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forEachStore(t, func(t *testing.T, store repositories.Store) {
+				newTestYard(t, store, NewRedisService(nil))
+				management := NewYardManagementService(store, NewRedisService(nil))
+				existing := testPlan(t, store)
+				if tt.existing != nil {
+					request := planRequest()
+					tt.existing(&request)
+					if _, err := management.UpdatePlan("YRD1", existing.ID, request); err != nil {
+						t.Fatalf("UpdatePlan: %v", err)
+					}
+				}
+
+				request := planRequest()
+				request.ContainerType = "REEFER"
+				tt.plan(&request)
+				_, err := management.CreatePlan("YRD1", request)
+				if !tt.wantErr {
+					if err != nil {
+						t.Fatalf("CreatePlan: %v", err)
+					}
+					return
+				}
+
+				wantCode(t, err, "plan_overlap")
+				var serviceErr *ServiceError
+				if !errors.As(err, &serviceErr) || serviceErr.Kind != KindConflict {
+					t.Fatalf("error = %v, want a conflict", err)
+				}
+				if conflict, ok := serviceErr.Details.(dto.PlanConflict); !ok || conflict.PlanID != existing.ID {
+					t.Fatalf("details = %+v, want the conflicting plan %d", serviceErr.Details, existing.ID)
+				}
+			})
+		})
+	}
+}
+
+func TestPlanUpdateOverlap(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		newTestYard(t, store, NewRedisService(nil))
+		management := NewYardManagementService(store, NewRedisService(nil))
+		existing := testPlan(t, store)
+
+		request := planRequest()
+		request.StartSlot, request.EndSlot = 4, 6
+		neighbour, err := management.CreatePlan("YRD1", request)
+		if err != nil {
+			t.Fatalf("CreatePlan: %v", err)
+		}
+
+		// A plan never overlaps itself.
+		request.EndSlot = 7
+		if _, err := management.UpdatePlan("YRD1", neighbour.ID, request); err != nil {
+			t.Fatalf("UpdatePlan: %v", err)
+		}
+
+		request.StartSlot = 3
+		_, err = management.UpdatePlan("YRD1", neighbour.ID, request)
+		wantCode(t, err, "plan_overlap")
+		if errorKind(err) != KindConflict {
+			t.Fatalf("error = %v, want a conflict with plan %d", err, existing.ID)
+		}
+	})
+}
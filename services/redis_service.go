@@ -3,64 +3,118 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sync/atomic"
 	"time"
 
 	"backend_yard_planning_system/config"
 	"backend_yard_planning_system/models"
+
+	"github.com/redis/go-redis/v9"
 )
 
-type RedisService struct{}
+// RedisService caches yard plans, block occupancy and suggestions. Redis is
+// optional: with a nil client every lookup misses and every write is a no-op,
+// and Redis errors are logged and treated as misses, so callers always fall
+// back to the database. After an error the cache is skipped for
+// CacheRetryAfter so an unreachable Redis does not slow every request down.
+type RedisService struct {
+	client    *redis.Client
+	downUntil atomic.Int64 // unix nanos
+}
 
-func NewRedisService() *RedisService {
-	return &RedisService{}
+func NewRedisService(client *redis.Client) *RedisService {
+	return &RedisService{client: client}
 }
 
+var errCacheDisabled = errors.New("redis cache is disabled")
+
+// CacheRetryAfter is how long the cache is bypassed after a Redis error.
+var CacheRetryAfter = 10 * time.Second
+
 // Cache keys
 const (
-	CacheKeyYardPlans      = "yard_plans:%s"             // yard_plans:YRD1
-	CacheKeyBlockOccupancy = "block_occupancy:%s"        // block_occupancy:LC01
-	CacheKeyContainer      = "container:%s"              // container:ALFI000001
-	CacheKeySuggestions    = "suggestions:%s:%d:%.1f:%s" // suggestions:YRD1:20:8.6:DRY
+	CacheKeyYardPlans      = "yard_plans:%s"              // yard_plans:YRD1
+	CacheKeyBlockOccupancy = "block_occupancy:%s"         // block_occupancy:3, bitmap planes of the cells
+	CacheKeyBlockHeights   = "block_heights:%s"           // block_heights:3, container height per cell
+	CacheKeyBlockVersion   = "block_occupancy_version:%s" // block_occupancy_version:3
+	CacheKeyContainer      = "container:%s"               // container:ALFI000001
+	CacheKeySuggestions    = "suggestions:%s:%d:%.1f:%s"  // suggestions:YRD1:20:8.6:DRY, followed by the block versions
 )
 
 // Cache durations
 var (
 	CacheDurationYardPlans      = 5 * time.Minute
-	CacheDurationBlockOccupancy = 2 * time.Minute
+	CacheDurationBlockOccupancy = 30 * time.Minute
 	CacheDurationContainer      = 10 * time.Minute
 	CacheDurationSuggestions    = 1 * time.Minute
 )
 
-func (r *RedisService) GetYardPlans(yardName string) ([]models.YardPlan, error) {
-	cacheKey := fmt.Sprintf(CacheKeyYardPlans, yardName)
-
-	// Try to get from cache
-	cachedData, err := config.RedisClient.Get(config.Ctx, cacheKey).Result()
-	if err == nil {
-		var plans []models.YardPlan
-		if err := json.Unmarshal([]byte(cachedData), &plans); err == nil {
-			log.Printf("✅ Cache HIT for yard plans: %s", yardName)
-			return plans, nil
-		}
-	}
+func (r *RedisService) enabled() bool {
+	return r != nil && r.client != nil && time.Now().UnixNano() >= r.downUntil.Load()
+}
 
-	log.Printf("❌ Cache MISS for yard plans: %s", yardName)
-	return nil, err
+// failed records a Redis error and bypasses the cache for CacheRetryAfter.
+func (r *RedisService) failed(action string, err error) {
+	r.downUntil.Store(time.Now().Add(CacheRetryAfter).UnixNano())
+	log.Printf("⚠️ Redis %s failed, bypassing cache for %s: %v", action, CacheRetryAfter, err)
 }
 
-func (r *RedisService) SetYardPlans(yardName string, plans []models.YardPlan) error {
-	cacheKey := fmt.Sprintf(CacheKeyYardPlans, yardName)
+func (r *RedisService) get(cacheKey string, dest interface{}) error {
+	if !r.enabled() {
+		return errCacheDisabled
+	}
 
-	jsonData, err := json.Marshal(plans)
+	cachedData, err := r.client.Get(config.Ctx, cacheKey).Bytes()
 	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			r.failed("read of "+cacheKey, err)
+		}
 		return err
 	}
+	return json.Unmarshal(cachedData, dest)
+}
 
-	err = config.RedisClient.Set(config.Ctx, cacheKey, jsonData, CacheDurationYardPlans).Err()
+func (r *RedisService) set(cacheKey string, value interface{}, ttl time.Duration) error {
+	if !r.enabled() {
+		return errCacheDisabled
+	}
+
+	jsonData, err := json.Marshal(value)
 	if err != nil {
-		log.Printf("❌ Failed to cache yard plans: %v", err)
+		return err
+	}
+	if err := r.client.Set(config.Ctx, cacheKey, jsonData, ttl).Err(); err != nil {
+		r.failed("write of "+cacheKey, err)
+		return err
+	}
+	return nil
+}
+
+func (r *RedisService) del(keys ...string) {
+	if !r.enabled() || len(keys) == 0 {
+		return
+	}
+	if err := r.client.Del(config.Ctx, keys...).Err(); err != nil {
+		r.failed(fmt.Sprintf("invalidation of %v", keys), err)
+	}
+}
+
+func (r *RedisService) GetYardPlans(yardName string) ([]models.YardPlan, error) {
+	var plans []models.YardPlan
+	if err := r.get(fmt.Sprintf(CacheKeyYardPlans, yardName), &plans); err != nil {
+		log.Printf("❌ Cache MISS for yard plans: %s", yardName)
+		return nil, err
+	}
+
+	log.Printf("✅ Cache HIT for yard plans: %s", yardName)
+	return plans, nil
+}
+
+func (r *RedisService) SetYardPlans(yardName string, plans []models.YardPlan) error {
+	if err := r.set(fmt.Sprintf(CacheKeyYardPlans, yardName), plans, CacheDurationYardPlans); err != nil {
 		return err
 	}
 
@@ -68,71 +122,210 @@ func (r *RedisService) SetYardPlans(yardName string, plans []models.YardPlan) er
 	return nil
 }
 
-func (r *RedisService) GetBlockOccupancy(blockID uint) (map[string]bool, error) {
-	cacheKey := fmt.Sprintf(CacheKeyBlockOccupancy, fmt.Sprintf("%d", blockID))
+func blockKeys(blockID uint) []string {
+	id := fmt.Sprintf("%d", blockID)
+	return []string{
+		fmt.Sprintf(CacheKeyBlockOccupancy, id),
+		fmt.Sprintf(CacheKeyBlockHeights, id),
+		fmt.Sprintf(CacheKeyBlockVersion, id),
+	}
+}
+
+// cachedBlockOccupancy is the occupancy of a block as kept in Redis: the
+// bitmap planes and height map described in occupancy.go, and the version
+// counter every change of the block increments.
+type cachedBlockOccupancy struct {
+	Version int64
+	Bitmap  []byte
+	Heights []byte
+	Found   bool // false when the maps are not cached
+}
 
-	cachedData, err := config.RedisClient.Get(config.Ctx, cacheKey).Result()
-	if err == nil {
-		var occupancy map[string]bool
-		if err := json.Unmarshal([]byte(cachedData), &occupancy); err == nil {
-			log.Printf("✅ Cache HIT for block occupancy: %d", blockID)
-			return occupancy, nil
-		}
+// cellHeight is the height map entry of one cell, in tenths of a foot.
+type cellHeight struct {
+	Cell   int64
+	Tenths byte
+}
+
+// GetBlockOccupancy reads the maps and version of a block in a single round
+// trip.
+func (r *RedisService) GetBlockOccupancy(blockID uint) (*cachedBlockOccupancy, error) {
+	if !r.enabled() {
+		return nil, errCacheDisabled
+	}
+
+	keys := blockKeys(blockID)
+	pipe := r.client.Pipeline()
+	bitmap := pipe.Get(config.Ctx, keys[0])
+	heights := pipe.Get(config.Ctx, keys[1])
+	version := pipe.Get(config.Ctx, keys[2])
+	if _, err := pipe.Exec(config.Ctx); err != nil && !errors.Is(err, redis.Nil) {
+		r.failed(fmt.Sprintf("read of block occupancy %d", blockID), err)
+		return nil, err
+	}
+
+	occupancy := &cachedBlockOccupancy{}
+	if v, err := version.Int64(); err == nil {
+		occupancy.Version = v
+	}
+	bits, bitsErr := bitmap.Bytes()
+	tenths, heightsErr := heights.Bytes()
+	if bitsErr != nil || heightsErr != nil {
+		log.Printf("❌ Cache MISS for block occupancy: %d", blockID)
+		return occupancy, nil
 	}
+	occupancy.Bitmap, occupancy.Heights, occupancy.Found = bits, tenths, true
 
-	log.Printf("❌ Cache MISS for block occupancy: %d", blockID)
-	return nil, err
+	log.Printf("✅ Cache HIT for block occupancy: %d", blockID)
+	return occupancy, nil
 }
 
-func (r *RedisService) SetBlockOccupancy(blockID uint, occupancy map[string]bool) error {
-	cacheKey := fmt.Sprintf(CacheKeyBlockOccupancy, fmt.Sprintf("%d", blockID))
+// storeBlockOccupancyScript replaces the cached occupancy of a block unless
+// the block changed since version was read.
+var storeBlockOccupancyScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[3]) or '0'
+if current ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[3], 'EX', ARGV[2])
+redis.call('SET', KEYS[2], ARGV[4], 'EX', ARGV[2])
+return 1
+`)
 
-	jsonData, err := json.Marshal(occupancy)
-	if err != nil {
-		return err
+// SetBlockOccupancy caches a block's occupancy rebuilt from the database. It
+// reports false when the block changed after version was read, in which case
+// the rebuilt state may be stale and is not stored.
+func (r *RedisService) SetBlockOccupancy(blockID uint, version int64, bitmap, heights []byte) (bool, error) {
+	if !r.enabled() {
+		return false, errCacheDisabled
 	}
 
-	err = config.RedisClient.Set(config.Ctx, cacheKey, jsonData, CacheDurationBlockOccupancy).Err()
+	args := []interface{}{version, int(CacheDurationBlockOccupancy.Seconds()), bitmap, heights}
+	stored, err := storeBlockOccupancyScript.Run(config.Ctx, r.client, blockKeys(blockID), args...).Int()
 	if err != nil {
-		log.Printf("❌ Failed to cache block occupancy: %v", err)
-		return err
+		r.failed(fmt.Sprintf("write of block occupancy %d", blockID), err)
+		return false, err
+	}
+	if stored == 0 {
+		log.Printf("ℹ️ Block %d changed while its occupancy was rebuilt, not caching", blockID)
+		return false, nil
 	}
 
 	log.Printf("✅ Cached block occupancy: %d", blockID)
+	return true, nil
+}
+
+// updateBlockOccupancyScript applies one change to the cached occupancy of a
+// block and bumps its version. A block whose maps are not both cached only
+// gets the version bump; the next read rebuilds them from the database.
+var updateBlockOccupancyScript = redis.NewScript(`
+redis.call('INCR', KEYS[3])
+if redis.call('EXISTS', KEYS[1]) == 0 or redis.call('EXISTS', KEYS[2]) == 0 then
+	redis.call('DEL', KEYS[1], KEYS[2])
+	return 0
+end
+local i = 4
+for n = 1, tonumber(ARGV[1]) do
+	redis.call('SETBIT', KEYS[1], ARGV[i], 0)
+	i = i + 1
+end
+for n = 1, tonumber(ARGV[2]) do
+	redis.call('SETBIT', KEYS[1], ARGV[i], 1)
+	i = i + 1
+end
+for n = 1, tonumber(ARGV[3]) do
+	redis.call('SETRANGE', KEYS[2], ARGV[i], string.char(tonumber(ARGV[i + 1])))
+	i = i + 2
+end
+return 1
+`)
+
+// UpdateBlockOccupancy atomically clears and sets bits of a block's bitmap and
+// writes cells of its height map, in that order.
+func (r *RedisService) UpdateBlockOccupancy(blockID uint, clear, set []int64, heights []cellHeight) error {
+	if !r.enabled() {
+		return errCacheDisabled
+	}
+
+	args := []interface{}{len(clear), len(set), len(heights)}
+	for _, bit := range clear {
+		args = append(args, bit)
+	}
+	for _, bit := range set {
+		args = append(args, bit)
+	}
+	for _, h := range heights {
+		args = append(args, h.Cell, h.Tenths)
+	}
+
+	if err := updateBlockOccupancyScript.Run(config.Ctx, r.client, blockKeys(blockID), args...).Err(); err != nil {
+		r.failed(fmt.Sprintf("update of block occupancy %d", blockID), err)
+		return err
+	}
 	return nil
 }
 
-func (r *RedisService) InvalidateBlockOccupancy(blockID uint) {
-	cacheKey := fmt.Sprintf(CacheKeyBlockOccupancy, fmt.Sprintf("%d", blockID))
-	err := config.RedisClient.Del(config.Ctx, cacheKey).Err()
+// DropBlockOccupancy forgets the cached occupancy of a block, e.g. after its
+// dimensions changed, and bumps its version.
+func (r *RedisService) DropBlockOccupancy(blockID uint) {
+	if !r.enabled() {
+		return
+	}
+
+	keys := blockKeys(blockID)
+	_, err := r.client.TxPipelined(config.Ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(config.Ctx, keys[2])
+		pipe.Del(config.Ctx, keys[0], keys[1])
+		return nil
+	})
 	if err != nil {
-		log.Printf("❌ Failed to invalidate block occupancy cache: %v", err)
-	} else {
-		log.Printf("✅ Invalidated block occupancy cache: %d", blockID)
+		r.failed(fmt.Sprintf("drop of block occupancy %d", blockID), err)
 	}
 }
 
 func (r *RedisService) InvalidateYardPlans(yardName string) {
-	cacheKey := fmt.Sprintf(CacheKeyYardPlans, yardName)
-	err := config.RedisClient.Del(config.Ctx, cacheKey).Err()
-	if err != nil {
-		log.Printf("❌ Failed to invalidate yard plans cache: %v", err)
-	} else {
-		log.Printf("✅ Invalidated yard plans cache: %s", yardName)
-	}
+	r.del(fmt.Sprintf(CacheKeyYardPlans, yardName))
 }
 
-func (r *RedisService) CacheSuggestion(yardName string, containerSize int, containerHeight float64, containerType string, position interface{}) error {
-	cacheKey := fmt.Sprintf(CacheKeySuggestions, yardName, containerSize, containerHeight, containerType)
+// BlockVersions returns the occupancy versions of the given blocks in a
+// single round trip. Blocks that never changed are at version 0.
+func (r *RedisService) BlockVersions(blockIDs []uint) ([]int64, error) {
+	if !r.enabled() {
+		return nil, errCacheDisabled
+	}
 
-	jsonData, err := json.Marshal(position)
+	keys := make([]string, 0, len(blockIDs))
+	for _, blockID := range blockIDs {
+		keys = append(keys, blockKeys(blockID)[2])
+	}
+	values, err := r.client.MGet(config.Ctx, keys...).Result()
 	if err != nil {
-		return err
+		r.failed("read of block versions", err)
+		return nil, err
 	}
 
-	err = config.RedisClient.Set(config.Ctx, cacheKey, jsonData, CacheDurationSuggestions).Err()
-	if err != nil {
-		log.Printf("❌ Failed to cache suggestion: %v", err)
+	versions := make([]int64, len(values))
+	for i, value := range values {
+		if str, ok := value.(string); ok {
+			fmt.Sscan(str, &versions[i])
+		}
+	}
+	return versions, nil
+}
+
+// SuggestionKey is the cache key of the suggestions for a container class
+// given the occupancy versions of the blocks its plans cover, so any change
+// to one of those blocks moves the suggestions to a new key.
+func SuggestionKey(yardName string, containerSize int, containerHeight float64, containerType string, blockIDs []uint, versions []int64) string {
+	key := fmt.Sprintf(CacheKeySuggestions, yardName, containerSize, containerHeight, containerType)
+	for i, blockID := range blockIDs {
+		key += fmt.Sprintf(":%d@%d", blockID, versions[i])
+	}
+	return key
+}
+
+func (r *RedisService) CacheSuggestion(cacheKey string, positions interface{}) error {
+	if err := r.set(cacheKey, positions, CacheDurationSuggestions); err != nil {
 		return err
 	}
 
@@ -140,25 +333,41 @@ func (r *RedisService) CacheSuggestion(yardName string, containerSize int, conta
 	return nil
 }
 
-func (r *RedisService) GetCachedSuggestion(yardName string, containerSize int, containerHeight float64, containerType string) (interface{}, error) {
-	cacheKey := fmt.Sprintf(CacheKeySuggestions, yardName, containerSize, containerHeight, containerType)
-
-	cachedData, err := config.RedisClient.Get(config.Ctx, cacheKey).Result()
-	if err != nil {
-		return nil, err
+// GetCachedSuggestion decodes the suggestion cached under cacheKey into dest.
+func (r *RedisService) GetCachedSuggestion(cacheKey string, dest interface{}) error {
+	if err := r.get(cacheKey, dest); err != nil {
+		return err
 	}
 
-	var position interface{}
-	if err := json.Unmarshal([]byte(cachedData), &position); err != nil {
-		return nil, err
+	log.Printf("✅ Cache HIT for suggestion: %s", cacheKey)
+	return nil
+}
+
+// InvalidateSuggestions drops the cached suggestions of every container class
+// in a yard.
+func (r *RedisService) InvalidateSuggestions(yardName string) {
+	if !r.enabled() {
+		return
 	}
 
-	log.Printf("✅ Cache HIT for suggestion: %s", cacheKey)
-	return position, nil
+	pattern := fmt.Sprintf("suggestions:%s:*", yardName)
+	iter := r.client.Scan(config.Ctx, 0, pattern, 100).Iterator()
+	var keys []string
+	for iter.Next(config.Ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		r.failed("scan of suggestions:"+yardName, err)
+		return
+	}
+	r.del(keys...)
 }
 
 // Health check
 func (r *RedisService) HealthCheck() error {
-	_, err := config.RedisClient.Ping(config.Ctx).Result()
+	if !r.enabled() {
+		return errCacheDisabled
+	}
+	_, err := r.client.Ping(config.Ctx).Result()
 	return err
 }
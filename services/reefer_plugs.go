@@ -0,0 +1,165 @@
+package services
+
+import (
+	"fmt"
+	"log"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
+)
+
+const (
+	ContainerTypeReefer = "REEFER"
+
+	CodeReeferUnpowered = "reefer_unpowered"
+)
+
+func loadReeferPlugs(tx repositories.Store, blockID uint) ([]models.ReeferPlug, error) {
+	return tx.ReeferPlugs().ListByBlock(blockID)
+}
+
+// placedReefers returns the reefers placed in a block, lowest tier first.
+func placedReefers(tx repositories.Store, blockID uint) ([]models.Container, error) {
+	placed, err := tx.Containers().ListPlaced(blockID)
+	if err != nil {
+		return nil, err
+	}
+	reefers := placed[:0]
+	for _, container := range placed {
+		if container.ContainerType == ContainerTypeReefer {
+			reefers = append(reefers, container)
+		}
+	}
+	return reefers, nil
+}
+
+// poweringPlug returns the plug that can power a reefer at f: a plug on the
+// same row under any slot of the footprint that reaches f's tier.
+func poweringPlug(plugs []models.ReeferPlug, f footprint) *models.ReeferPlug {
+	for slot := f.StartSlot; slot <= f.EndSlot(); slot++ {
+		for i := range plugs {
+			if plugs[i].Slot == slot && plugs[i].Row == f.Row && plugs[i].Tiers >= f.Tier {
+				return &plugs[i]
+			}
+		}
+	}
+	return nil
+}
+
+func checkReeferPower(f footprint, containerType string, plugs []models.ReeferPlug, blockName string) error {
+	if containerType != ContainerTypeReefer || poweringPlug(plugs, f) != nil {
+		return nil
+	}
+	return &ServiceError{
+		Kind: KindRuleViolation,
+		Code: CodeReeferUnpowered,
+		Message: fmt.Sprintf("no reefer plug powers slot %d-%d, row %d, tier %d in block %s",
+			f.StartSlot, f.EndSlot(), f.Row, f.Tier, blockName),
+	}
+}
+
+// SetReeferPlugs replaces the power points of a block. Plugs that currently
+// power a placed reefer cannot be removed.
+func (s *YardManagementService) SetReeferPlugs(yardName, blockName string, req dto.ReeferPlugsRequest) (*dto.ReeferUtilizationResponse, error) {
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		block, err := findBlockByName(tx, yardName, blockName)
+		if err != nil {
+			return err
+		}
+
+		plugs := make([]models.ReeferPlug, 0, len(req.Plugs))
+		seen := make(map[string]bool)
+		for _, p := range req.Plugs {
+			if p.Slot > block.MaxSlot || p.Row > block.MaxRow || p.Tiers > block.MaxTier {
+				return newInvalidError("plug_outside_block",
+					fmt.Sprintf("reefer plug at slot %d, row %d with %d tiers does not fit block %s (%d slots, %d rows, %d tiers)",
+						p.Slot, p.Row, p.Tiers, block.Name, block.MaxSlot, block.MaxRow, block.MaxTier))
+			}
+			key := getPositionKey(p.Slot, p.Row, 0)
+			if seen[key] {
+				return newInvalidError("duplicate_plug",
+					fmt.Sprintf("reefer plug at slot %d, row %d is listed more than once", p.Slot, p.Row))
+			}
+			seen[key] = true
+
+			plugs = append(plugs, models.ReeferPlug{BlockID: block.ID, Slot: p.Slot, Row: p.Row, Tiers: p.Tiers})
+		}
+
+		reefers, err := placedReefers(tx, block.ID)
+		if err != nil {
+			return err
+		}
+		for _, reefer := range reefers {
+			if poweringPlug(plugs, containerFootprint(reefer)) == nil {
+				return newConflictError("reefer_plug_in_use",
+					fmt.Sprintf("reefer %s at slot %d, row %d, tier %d would lose power",
+						reefer.ContainerNumber, reefer.Slot, reefer.Row, reefer.Tier))
+			}
+		}
+
+		if err := tx.ReeferPlugs().Replace(block.ID, plugs); err != nil {
+			return err
+		}
+
+		log.Printf("✅ Reefer plugs updated for block %s: %d plug(s)", block.Name, len(plugs))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.InvalidateSuggestions(yardName)
+	return s.GetReeferUtilization(yardName, blockName)
+}
+
+// GetReeferUtilization reports, for every plug of a block, how many of the
+// tiers it can power are taken by placed reefers.
+func (s *YardManagementService) GetReeferUtilization(yardName, blockName string) (*dto.ReeferUtilizationResponse, error) {
+	block, err := findBlockByName(s.store, yardName, blockName)
+	if err != nil {
+		return nil, err
+	}
+
+	plugs, err := loadReeferPlugs(s.store, block.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	reefers, err := placedReefers(s.store, block.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[uint]*dto.ReeferPlugUsage, len(plugs))
+	response := &dto.ReeferUtilizationResponse{
+		Yard:      yardName,
+		Block:     block.Name,
+		Plugs:     len(plugs),
+		Positions: make([]dto.ReeferPlugUsage, 0, len(plugs)),
+	}
+	for _, plug := range plugs {
+		usage[plug.ID] = &dto.ReeferPlugUsage{Slot: plug.Slot, Row: plug.Row, Tiers: plug.Tiers, Containers: []string{}}
+		response.Capacity += plug.Tiers
+	}
+
+	for _, reefer := range reefers {
+		plug := poweringPlug(plugs, containerFootprint(reefer))
+		if plug == nil {
+			log.Printf("⚠️ Reefer %s in block %s is not on a powered position", reefer.ContainerNumber, block.Name)
+			continue
+		}
+		usage[plug.ID].Used++
+		usage[plug.ID].Containers = append(usage[plug.ID].Containers, reefer.ContainerNumber)
+		response.Used++
+	}
+
+	for _, plug := range plugs {
+		response.Positions = append(response.Positions, *usage[plug.ID])
+	}
+	if response.Capacity > 0 {
+		response.Utilization = float64(response.Used) / float64(response.Capacity)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/repositories"
+)
+
+// newReeferYard adds a REEFER plan over slots 4-5 of the test yard, with a
+// two-tier plug at slot 4 row 1 and a one-tier plug at slot 5 row 2.
+func newReeferYard(t *testing.T, store repositories.Store) (*YardService, *YardManagementService) {
+	t.Helper()
+	s := newTestYard(t, store, NewRedisService(nil))
+	management := NewYardManagementService(store, NewRedisService(nil))
+
+	plan := planRequest()
+	plan.ContainerType = ContainerTypeReefer
+	plan.StartSlot, plan.EndSlot = 4, 5
+	if _, err := management.CreatePlan("YRD1", plan); err != nil {
+		t.Fatalf("CreatePlan: %v", err)
+	}
+	if _, err := management.SetReeferPlugs("YRD1", "LC01", dto.ReeferPlugsRequest{Plugs: []dto.ReeferPlugRequest{
+		{Slot: 4, Row: 1, Tiers: 2},
+		{Slot: 5, Row: 2, Tiers: 1},
+	}}); err != nil {
+		t.Fatalf("SetReeferPlugs: %v", err)
+	}
+	return s, management
+}
+
+func reeferPlacement(containerNumber string, slot, row, tier int) dto.PlacementRequest {
+	request := placementRequest(containerNumber, slot, row, tier)
+	request.ContainerType = ContainerTypeReefer
+	return request
+}
+
+func TestReeferNeedsPlug(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s, _ := newReeferYard(t, store)
+
+		err := s.PlaceContainer(reeferPlacement("REEF0001", 4, 2, 1))
+		wantCode(t, err, CodeReeferUnpowered)
+		if errorKind(err) != KindRuleViolation {
+			t.Fatalf("error = %v, want a rule violation", err)
+		}
+
+		for _, tier := range []int{1, 2} {
+			if err := s.PlaceContainer(reeferPlacement(fmt.Sprintf("REEF000%d", tier+1), 4, 1, tier)); err != nil {
+				t.Fatalf("PlaceContainer at 4/1/%d: %v", tier, err)
+			}
+		}
+		// The plug reaches two tiers only.
+		wantCode(t, s.PlaceContainer(reeferPlacement("REEF0004", 4, 1, 3)), CodeReeferUnpowered)
+
+		request := suggestionRequest("REEF0005")
+		request.ContainerType = ContainerTypeReefer
+		response, err := s.GetSuggestion(request, 5)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		if len(response.RankedPositions) != 1 {
+			t.Fatalf("ranked %v, want only the free powered position", response.RankedPositions)
+		}
+		wantPosition(t, response.SuggestedPosition, 5, 2, 1)
+	})
+}
+
+func TestReeferUtilization(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s, management := newReeferYard(t, store)
+		if err := s.PlaceContainer(reeferPlacement("REEF0001", 4, 1, 1)); err != nil {
+			t.Fatalf("PlaceContainer: %v", err)
+		}
+		if err := s.PlaceContainer(reeferPlacement("REEF0002", 4, 1, 2)); err != nil {
+			t.Fatalf("PlaceContainer: %v", err)
+		}
+		place(t, s, "CONT0001", 1, 1, 1)
+
+		got, err := management.GetReeferUtilization("YRD1", "LC01")
+		if err != nil {
+			t.Fatalf("GetReeferUtilization: %v", err)
+		}
+		want := &dto.ReeferUtilizationResponse{
+			Yard: "YRD1", Block: "LC01", Plugs: 2, Capacity: 3, Used: 2, Utilization: 2.0 / 3,
+			Positions: []dto.ReeferPlugUsage{
+				{Slot: 4, Row: 1, Tiers: 2, Used: 2, Containers: []string{"REEF0001", "REEF0002"}},
+				{Slot: 5, Row: 2, Tiers: 1, Used: 0, Containers: []string{}},
+			},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("utilization = %+v, want %+v", got, want)
+		}
+
+		// The plug powering them cannot be removed.
+		_, err = management.SetReeferPlugs("YRD1", "LC01", dto.ReeferPlugsRequest{Plugs: []dto.ReeferPlugRequest{{Slot: 5, Row: 2, Tiers: 1}}})
+		wantCode(t, err, "reefer_plug_in_use")
+	})
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
+)
+
+const (
+	CodeNoRehandlePosition = "no_rehandle_position"
+
+	// MoveReasonRehandle marks moves made to clear the way for a pickup.
+	MoveReasonRehandle = "REHANDLE"
+)
+
+// rehandle is a planned move of a blocker to a free position.
+type rehandle struct {
+	container models.Container
+	block     models.Block
+	to        footprint
+}
+
+// blockersOf returns the containers that must be moved before target can be
+// lifted: everything stacked on it and, since a 40ft may also rest on a
+// neighbouring stack, everything stacked on those. Topmost come first.
+func blockersOf(tx repositories.Store, target models.Container) ([]models.Container, error) {
+	seen := map[uint]bool{target.ID: true}
+	queue := []models.Container{target}
+	var blockers []models.Container
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		above, err := containersAbove(tx, current.BlockID, containerFootprint(current))
+		if err != nil {
+			return nil, err
+		}
+		for _, container := range above {
+			if seen[container.ID] {
+				continue
+			}
+			seen[container.ID] = true
+			blockers = append(blockers, container)
+			queue = append(queue, container)
+		}
+	}
+
+	sort.SliceStable(blockers, func(i, j int) bool { return blockers[i].Tier > blockers[j].Tier })
+	return blockers, nil
+}
+
+func blockedPickupError(target models.Container, blockers []models.Container) error {
+	details := make([]dto.Blocker, 0, len(blockers))
+	for _, blocker := range blockers {
+		details = append(details, dto.Blocker{
+			ContainerNumber: blocker.ContainerNumber,
+			Position: dto.Position{
+				Block: target.Block.Name,
+				Slot:  blocker.Slot,
+				Row:   blocker.Row,
+				Tier:  blocker.Tier,
+			},
+		})
+	}
+	return &ServiceError{
+		Kind: KindRuleViolation,
+		Code: CodeContainersAbove,
+		Message: fmt.Sprintf("container %s has %d container(s) stacked on it; plan or execute rehandles to pick it up",
+			target.ContainerNumber, len(blockers)),
+		Details: details,
+	}
+}
+
+// planRehandles finds a destination for every blocker, topmost first, using
+// the suggestion engine. Destinations never lie in a stack that is being dug
+// out, and each planned destination is taken for the blockers after it.
+func (s *YardService) planRehandles(tx repositories.Store, target models.Container, blockers []models.Container) ([]rehandle, error) {
+	scale, err := loadWeightScale(tx, target.Block.YardID)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := make(map[uint][]footprint)
+	for _, container := range append([]models.Container{target}, blockers...) {
+		f := containerFootprint(container)
+		for slot := f.StartSlot; slot <= f.EndSlot(); slot++ {
+			for tier := 1; tier <= target.Block.MaxTier; tier++ {
+				excluded[target.BlockID] = append(excluded[target.BlockID], footprint{StartSlot: slot, Span: 1, Row: f.Row, Tier: tier})
+			}
+		}
+	}
+
+	rehandles := make([]rehandle, 0, len(blockers))
+	for _, blocker := range blockers {
+		attrs := containerAttributes{
+			Size:        blocker.ContainerSize,
+			Height:      blocker.ContainerHeight,
+			Type:        blocker.ContainerType,
+			SizeType:    blocker.SizeType,
+			OutOfGauge:  blocker.OutOfGauge,
+			GrossWeight: blocker.GrossWeight,
+
+			ExpectedDeparture: blocker.ExpectedDeparture,
+			VesselVoyage:      blocker.VesselVoyage,
+		}
+
+		plans, err := matchingPlans(tx, target.Block.YardID, attrs)
+		if err != nil {
+			return nil, err
+		}
+		ranked, err := s.rankAcrossPlans(tx, plans, attrs, blocker.ContainerNumber, excluded, scale, 1)
+		if err != nil {
+			return nil, &ServiceError{
+				Kind:    KindRuleViolation,
+				Code:    CodeNoRehandlePosition,
+				Message: fmt.Sprintf("no position to rehandle container %s to: %v", blocker.ContainerNumber, err),
+			}
+		}
+
+		best := ranked[0]
+		to := newFootprint(best.Slot, best.Row, best.Tier, blocker.ContainerSize)
+		excluded[best.plan.BlockID] = append(excluded[best.plan.BlockID], to)
+		rehandles = append(rehandles, rehandle{container: blocker, block: best.plan.Block, to: to})
+	}
+	return rehandles, nil
+}
+
+// pickupLocks returns the blocks a pickup locks. Executed rehandles move
+// blockers to blocks only known once they are planned, so such a pickup locks
+// every block of the yard up front rather than adding locks out of id order
+// partway through its transaction.
+func pickupLocks(tx repositories.Store, yardName string, blockID uint, executeRehandles bool) ([]uint, error) {
+	if !executeRehandles {
+		return []uint{blockID}, nil
+	}
+
+	yard, err := findYardByName(tx, yardName)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := tx.Blocks().ListByYard(yard.ID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(blocks))
+	for _, block := range blocks {
+		ids = append(ids, block.ID)
+	}
+	return ids, nil
+}
+
+// executeRehandles performs planned rehandles in order. The caller holds the
+// locks of every block of the yard. Every move is checked again, so a plan
+// that no longer holds fails instead of breaking a stack.
+func executeRehandles(tx repositories.Store, rehandles []rehandle, user string, changes *occupancyChanges) error {
+	for _, r := range rehandles {
+		container := r.container
+		if _, err := moveContainer(tx, &container, r.block, r.to, MoveReasonRehandle, user, false, changes); err != nil {
+			log.Printf("❌ Rehandle of %s failed: %v", container.ContainerNumber, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func rehandleMoves(from models.Block, rehandles []rehandle) []dto.RehandleMove {
+	moves := make([]dto.RehandleMove, 0, len(rehandles))
+	for _, r := range rehandles {
+		moves = append(moves, dto.RehandleMove{
+			ContainerNumber: r.container.ContainerNumber,
+			From: dto.Position{
+				Block: from.Name,
+				Slot:  r.container.Slot,
+				Row:   r.container.Row,
+				Tier:  r.container.Tier,
+			},
+			To: dto.Position{
+				Block: r.block.Name,
+				Slot:  r.to.StartSlot,
+				Row:   r.to.Row,
+				Tier:  r.to.Tier,
+			},
+		})
+	}
+	return moves
+}
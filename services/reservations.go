@@ -0,0 +1,108 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
+)
+
+const CodePositionReserved = "position_reserved"
+
+// reservedFootprints returns the footprints reserved in a block for containers
+// other than containerNumber.
+func reservedFootprints(tx repositories.Store, blockID uint, containerNumber string) ([]footprint, error) {
+	reservations, err := tx.Suggestions().ListActiveByBlock(blockID)
+	if err != nil {
+		return nil, err
+	}
+
+	footprints := make([]footprint, 0, len(reservations))
+	for _, r := range reservations {
+		if r.ContainerNumber != containerNumber {
+			footprints = append(footprints, reservationFootprint(r))
+		}
+	}
+	return footprints, nil
+}
+
+func reservationFootprint(r models.Suggestion) footprint {
+	span := r.SlotSpan
+	if span < 1 {
+		span = slotSpanForSize(r.ContainerSize)
+	}
+	return footprint{StartSlot: r.Slot, Span: span, Row: r.Row, Tier: r.Tier}
+}
+
+// checkReservation rejects placing containerNumber on cells another container
+// holds a reservation for.
+func checkReservation(tx repositories.Store, blockID uint, f footprint, containerNumber string) error {
+	reservations, err := tx.Suggestions().ListActiveByBlock(blockID)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range reservations {
+		reserved := reservationFootprint(r)
+		if r.ContainerNumber == containerNumber || reserved.Row != f.Row || reserved.Tier != f.Tier ||
+			reserved.StartSlot > f.EndSlot() || reserved.EndSlot() < f.StartSlot {
+			continue
+		}
+		return newConflictError(CodePositionReserved,
+			fmt.Sprintf("position is reserved for container %s until %s", r.ContainerNumber, r.ExpiresAt.Format(time.RFC3339)))
+	}
+	return nil
+}
+
+// ListReservations returns the active reservations of a yard, soonest to
+// expire first.
+func (s *YardService) ListReservations(yardName string) ([]dto.Reservation, error) {
+	yard, err := findYardByName(s.store, yardName)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions, err := s.store.Suggestions().ListActiveByYard(yard.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	reservations := make([]dto.Reservation, 0, len(suggestions))
+	for _, r := range suggestions {
+		reservations = append(reservations, dto.Reservation{
+			ContainerNumber: r.ContainerNumber,
+			Block:           r.Block.Name,
+			Slot:            r.Slot,
+			Row:             r.Row,
+			Tier:            r.Tier,
+			ContainerSize:   r.ContainerSize,
+			ExpiresAt:       r.ExpiresAt,
+		})
+	}
+	return reservations, nil
+}
+
+// CancelReservation releases the position reserved for a container. The
+// suggestion itself is kept so a later placement can still reuse its
+// attributes.
+func (s *YardService) CancelReservation(yardName, containerNumber string) error {
+	yard, err := findYardByName(s.store, yardName)
+	if err != nil {
+		return err
+	}
+
+	expired, err := s.store.Suggestions().Expire(yard.ID, containerNumber)
+	if err != nil {
+		return err
+	}
+	if !expired {
+		return newNotFoundError("reservation_not_found",
+			fmt.Sprintf("no active reservation for container %s in yard %s", containerNumber, yard.Name))
+	}
+
+	log.Printf("🔓 Reservation cancelled for %s in yard %s", containerNumber, yard.Name)
+	return nil
+}
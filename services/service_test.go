@@ -0,0 +1,208 @@
+package services
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"backend_yard_planning_system/database"
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
+
+	"gorm.io/gorm/logger"
+)
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Verbose() {
+		log.SetOutput(io.Discard)
+	}
+	os.Exit(m.Run())
+}
+
+// testStores builds a fresh, empty store of every kind the services run on.
+var testStores = map[string]func(t *testing.T) repositories.Store{
+	"memory": func(*testing.T) repositories.Store { return repositories.NewMemoryStore() },
+	"sqlite": newSQLiteStore,
+}
+
+// newSQLiteStore opens and migrates an in-memory SQLite database of its own.
+func newSQLiteStore(t *testing.T) repositories.Store {
+	t.Helper()
+	db, err := database.Open(database.DriverSQLite, ":memory:")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("DB: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	return repositories.NewGormStore(db)
+}
+
+// forEachStore runs test against a fresh store of every kind.
+func forEachStore(t *testing.T, test func(t *testing.T, store repositories.Store)) {
+	t.Helper()
+	for name, newStore := range testStores {
+		t.Run(name, func(t *testing.T) {
+			test(t, newStore(t))
+		})
+	}
+}
+
+// newTestYard creates yard YRD1 with block LC01 of 10 slots, 5 rows and 4
+// tiers, where a LEFT_TO_RIGHT plan takes 20ft 8.6ft DRY containers on slots
+// 1-3, and returns the yard service over store.
+func newTestYard(t *testing.T, store repositories.Store, cache *RedisService) *YardService {
+	t.Helper()
+
+	management := NewYardManagementService(store, cache)
+	if _, err := management.CreateYard(dto.YardRequest{Name: "YRD1"}); err != nil {
+		t.Fatalf("CreateYard: %v", err)
+	}
+	if _, err := management.CreateBlock("YRD1", dto.BlockRequest{Name: "LC01", MaxSlot: 10, MaxRow: 5, MaxTier: 4}); err != nil {
+		t.Fatalf("CreateBlock: %v", err)
+	}
+	if _, err := management.CreatePlan("YRD1", planRequest()); err != nil {
+		t.Fatalf("CreatePlan: %v", err)
+	}
+	return NewYardService(store, cache)
+}
+
+// planRequest is the plan newTestYard creates: 20ft 8.6 DRY over slots 1-3,
+// rows 1-5 of LC01.
+func planRequest() dto.YardPlanRequest {
+	return dto.YardPlanRequest{
+		Block:             "LC01",
+		ContainerSize:     20,
+		ContainerHeight:   8.6,
+		ContainerType:     "DRY",
+		StartSlot:         1,
+		EndSlot:           3,
+		StartRow:          1,
+		EndRow:            5,
+		PriorityDirection: "LEFT_TO_RIGHT",
+	}
+}
+
+// testPlan returns the plan newTestYard created.
+func testPlan(t *testing.T, store repositories.Store) models.YardPlan {
+	t.Helper()
+	yard, err := store.Yards().FindByName("YRD1")
+	if err != nil {
+		t.Fatalf("FindByName: %v", err)
+	}
+	plans, err := store.Plans().ListByYard(yard.ID)
+	if err != nil || len(plans) != 1 {
+		t.Fatalf("ListByYard = %d plans, %v; want the test plan", len(plans), err)
+	}
+	return plans[0]
+}
+
+func suggestionRequest(containerNumber string) dto.SuggestionRequest {
+	return dto.SuggestionRequest{
+		Yard:            "YRD1",
+		ContainerNumber: containerNumber,
+		ContainerSize:   20,
+		ContainerHeight: 8.6,
+		ContainerType:   "DRY",
+	}
+}
+
+func placementRequest(containerNumber string, slot, row, tier int) dto.PlacementRequest {
+	return dto.PlacementRequest{
+		Yard:            "YRD1",
+		ContainerNumber: containerNumber,
+		Block:           "LC01",
+		Slot:            slot,
+		Row:             row,
+		Tier:            tier,
+		ContainerSize:   20,
+		ContainerHeight: 8.6,
+		ContainerType:   "DRY",
+	}
+}
+
+// place places a 20ft DRY container and fails the test on error.
+func place(t *testing.T, s *YardService, containerNumber string, slot, row, tier int) {
+	t.Helper()
+	if err := s.PlaceContainer(placementRequest(containerNumber, slot, row, tier)); err != nil {
+		t.Fatalf("PlaceContainer(%s at %d/%d/%d): %v", containerNumber, slot, row, tier, err)
+	}
+}
+
+func wantPosition(t *testing.T, got dto.Position, slot, row, tier int) {
+	t.Helper()
+	if got.Block != "LC01" || got.Slot != slot || got.Row != row || got.Tier != tier {
+		t.Fatalf("position = %+v, want LC01 %d/%d/%d", got, slot, row, tier)
+	}
+}
+
+func wantCode(t *testing.T, err error, code string) {
+	t.Helper()
+	if got := errorCode(err); got != code {
+		t.Fatalf("error = %v (code %q), want code %q", err, got, code)
+	}
+}
+
+// hookedStore wraps a store, inside its transactions too, to run hooks at
+// chosen calls, standing in for whatever concurrent requests did meanwhile.
+type hookedStore struct {
+	repositories.Store
+	hooks *storeHooks
+}
+
+type storeHooks struct {
+	beforeLock   func(tx repositories.Store) // runs before Blocks().Lock
+	beforeCreate func(tx repositories.Store) // runs before Containers().Create
+}
+
+func newHookedStore(store repositories.Store) *hookedStore {
+	return &hookedStore{Store: store, hooks: &storeHooks{}}
+}
+
+func (h *hookedStore) Transaction(fn func(tx repositories.Store) error) error {
+	return h.Store.Transaction(func(tx repositories.Store) error {
+		return fn(&hookedStore{Store: tx, hooks: h.hooks})
+	})
+}
+
+func (h *hookedStore) Blocks() repositories.BlockRepository {
+	return hookedBlocks{h.Store.Blocks(), h}
+}
+
+func (h *hookedStore) Containers() repositories.ContainerRepository {
+	return hookedContainers{h.Store.Containers(), h}
+}
+
+type hookedBlocks struct {
+	repositories.BlockRepository
+	store *hookedStore
+}
+
+func (b hookedBlocks) Lock(ids ...uint) error {
+	if hook := b.store.hooks.beforeLock; hook != nil {
+		hook(b.store.Store)
+	}
+	return b.BlockRepository.Lock(ids...)
+}
+
+type hookedContainers struct {
+	repositories.ContainerRepository
+	store *hookedStore
+}
+
+func (c hookedContainers) Create(container *models.Container) error {
+	if hook := c.store.hooks.beforeCreate; hook != nil {
+		hook(c.store.Store)
+	}
+	return c.ContainerRepository.Create(container)
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+
+	"backend_yard_planning_system/models"
+)
+
+// Stacking rule violation codes returned to clients.
+const (
+	CodeUnsupportedStack       = "unsupported_stack"
+	CodeMidSpanStack           = "mid_span_stack"
+	CodeFortyOnTwentiesBlocked = "forty_on_twenties_not_allowed"
+	CodeStackHeightExceeded    = "stack_height_exceeded"
+	CodeStackOnSpecialCargo    = "stack_on_special_cargo"
+	CodeSpecialCargoNotOnTop   = "special_cargo_not_on_top"
+	CodeStackOnShortContainer  = "stack_on_short_container"
+
+	ContainerTypeOpenTop  = "OPEN_TOP"
+	ContainerTypeFlatRack = "FLAT_RACK"
+)
+
+// closesStack reports whether nothing may be stacked on top of a container:
+// open-tops and flat racks have no roof to carry load and out-of-gauge cargo
+// sticks out.
+func closesStack(containerType string, outOfGauge bool) bool {
+	return containerType == ContainerTypeOpenTop || containerType == ContainerTypeFlatRack || outOfGauge
+}
+
+func (a containerAttributes) closesStack() bool {
+	return closesStack(a.Type, a.OutOfGauge)
+}
+
+// checkTopOfStack rejects an open-top, flat rack or out-of-gauge container at f when
+// something already sits above it.
+func checkTopOfStack(f footprint, attrs containerAttributes, occupiedAbove bool) error {
+	if !attrs.closesStack() || !occupiedAbove {
+		return nil
+	}
+	return &ServiceError{
+		Kind: KindRuleViolation,
+		Code: CodeSpecialCargoNotOnTop,
+		Message: fmt.Sprintf("open-top, flat rack and out-of-gauge containers must be the top of their stack; slot %d-%d, row %d is occupied above tier %d",
+			f.StartSlot, f.EndSlot(), f.Row, f.Tier),
+	}
+}
+
+// checkStackSupport verifies that a container of the given size with
+// footprint f rests on the containers directly below it. Above tier 1 every
+// cell of f must be supported, nothing may rest on open-top, flat rack or
+// out-of-gauge cargo, only a 10ft may rest on a 10ft, no supporting box may
+// stick out past f (its corner castings would land on a
+// mid-span gap), and a 40ft may only sit on two 20fts when the block allows it.
+func checkStackSupport(f footprint, containerSize int, below []models.Container, allowFortyOnTwenties bool) error {
+	if f.Tier <= 1 {
+		return nil
+	}
+
+	supported := make(map[int]bool)
+	for _, container := range below {
+		support := containerFootprint(container)
+		if support.Row != f.Row || support.Tier != f.Tier-1 ||
+			support.EndSlot() < f.StartSlot || support.StartSlot > f.EndSlot() {
+			continue
+		}
+
+		if closesStack(container.ContainerType, container.OutOfGauge) {
+			return &ServiceError{
+				Kind: KindRuleViolation,
+				Code: CodeStackOnSpecialCargo,
+				Message: fmt.Sprintf("container below (%s) is open-top, flat rack or out-of-gauge; nothing may be stacked on it",
+					container.ContainerNumber),
+			}
+		}
+		if container.ContainerSize == 10 && containerSize != 10 {
+			return &ServiceError{
+				Kind: KindRuleViolation,
+				Code: CodeStackOnShortContainer,
+				Message: fmt.Sprintf("container below (%s) is 10ft; only 10ft containers may be stacked on it",
+					container.ContainerNumber),
+			}
+		}
+		if support.StartSlot < f.StartSlot || support.EndSlot() > f.EndSlot() {
+			return &ServiceError{
+				Kind: KindRuleViolation,
+				Code: CodeMidSpanStack,
+				Message: fmt.Sprintf("container below (%s, slots %d-%d) does not line up with slots %d-%d; corners would rest on a mid-span gap",
+					container.ContainerNumber, support.StartSlot, support.EndSlot(), f.StartSlot, f.EndSlot()),
+			}
+		}
+		if support.Span < f.Span && !allowFortyOnTwenties {
+			return &ServiceError{
+				Kind:    KindRuleViolation,
+				Code:    CodeFortyOnTwentiesBlocked,
+				Message: "this block does not allow stacking a 40ft container on two 20ft containers",
+			}
+		}
+
+		for slot := support.StartSlot; slot <= support.EndSlot(); slot++ {
+			supported[slot] = true
+		}
+	}
+
+	for slot := f.StartSlot; slot <= f.EndSlot(); slot++ {
+		if !supported[slot] {
+			return &ServiceError{
+				Kind: KindRuleViolation,
+				Code: CodeUnsupportedStack,
+				Message: fmt.Sprintf("slot %d, row %d has no container at tier %d to support tier %d",
+					slot, f.Row, f.Tier-1, f.Tier),
+			}
+		}
+	}
+
+	return nil
+}
+
+// stackHeightBelow returns the height in feet of the tallest column under f,
+// summing the heights of the containers at lower tiers on the same row.
+func stackHeightBelow(f footprint, containers []models.Container) float64 {
+	tallest := 0.0
+	for slot := f.StartSlot; slot <= f.EndSlot(); slot++ {
+		column := 0.0
+		for _, container := range containers {
+			below := containerFootprint(container)
+			if below.Row == f.Row && below.Tier < f.Tier &&
+				slot >= below.StartSlot && slot <= below.EndSlot() {
+				column += container.ContainerHeight
+			}
+		}
+		if column > tallest {
+			tallest = column
+		}
+	}
+	return tallest
+}
+
+// checkStackHeight rejects a container of the given height at f when the stack
+// would exceed the block's clearance. Blocks without a clearance only limit the
+// tier count.
+func checkStackHeight(f footprint, containerHeight float64, containers []models.Container, block models.Block) error {
+	if block.MaxStackHeight == nil {
+		return nil
+	}
+
+	total := stackHeightBelow(f, containers) + containerHeight
+	if total > *block.MaxStackHeight+0.001 {
+		return &ServiceError{
+			Kind: KindRuleViolation,
+			Code: CodeStackHeightExceeded,
+			Message: fmt.Sprintf("stack would be %.1fft high at tier %d, exceeding block %s clearance of %.1fft",
+				total, f.Tier, block.Name, *block.MaxStackHeight),
+		}
+	}
+	return nil
+}
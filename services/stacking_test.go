@@ -0,0 +1,249 @@
+package services
+
+import (
+	"testing"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
+)
+
+// updateTestBlock changes the settings of the test yard's block LC01.
+func updateTestBlock(t *testing.T, store repositories.Store, change func(*dto.BlockRequest)) {
+	t.Helper()
+	request := dto.BlockRequest{Name: "LC01", MaxSlot: 10, MaxRow: 5, MaxTier: 4}
+	change(&request)
+	if _, err := NewYardManagementService(store, NewRedisService(nil)).UpdateBlock("YRD1", "LC01", request); err != nil {
+		t.Fatalf("UpdateBlock: %v", err)
+	}
+}
+
+// TestFortyOnTwenties stacks a 40ft on two 20fts placed before their plan was
+// turned into a 40ft plan.
+func TestFortyOnTwenties(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+		management := NewYardManagementService(store, NewRedisService(nil))
+
+		plan := planRequest()
+		plan.StartSlot, plan.EndSlot = 4, 5
+		created, err := management.CreatePlan("YRD1", plan)
+		if err != nil {
+			t.Fatalf("CreatePlan: %v", err)
+		}
+		place(t, s, "CONT0001", 4, 1, 1)
+		place(t, s, "CONT0002", 5, 1, 1)
+		plan.ContainerSize = 40
+		if _, err := management.UpdatePlan("YRD1", created.ID, plan); err != nil {
+			t.Fatalf("UpdatePlan: %v", err)
+		}
+
+		forty := placementRequest("CONT0003", 4, 1, 2)
+		forty.ContainerSize = 40
+		err = s.PlaceContainer(forty)
+		wantCode(t, err, CodeFortyOnTwentiesBlocked)
+		if errorKind(err) != KindRuleViolation {
+			t.Fatalf("error = %v, want a rule violation", err)
+		}
+
+		updateTestBlock(t, store, func(r *dto.BlockRequest) { r.AllowFortyOnTwenties = true })
+		if err := s.PlaceContainer(forty); err != nil {
+			t.Fatalf("PlaceContainer on two 20fts in a block allowing it: %v", err)
+		}
+	})
+}
+
+func TestCheckStackHeight(t *testing.T) {
+	clearance := func(feet float64) *float64 { return &feet }
+	tall := box("T1", 20, 2, 1, 1)
+	tall.ContainerHeight = 9.6
+	below := []models.Container{box("A1", 20, 1, 1, 1), box("A2", 20, 1, 1, 2), tall}
+
+	tests := []struct {
+		name      string
+		f         footprint
+		height    float64
+		clearance *float64
+		wantCode  string
+	}{
+		{"no clearance", newFootprint(1, 1, 3, 20), 9.6, nil, ""},
+		{"under the clearance", newFootprint(1, 1, 3, 20), 8.6, clearance(26), ""},
+		{"exactly the clearance", newFootprint(1, 1, 3, 20), 8.6, clearance(25.8), ""},
+		{"over the clearance", newFootprint(1, 1, 3, 20), 9.6, clearance(25.8), CodeStackHeightExceeded},
+		{"40ft measured on its taller column", newFootprint(1, 1, 2, 40), 8.6, clearance(18), CodeStackHeightExceeded},
+		{"40ft under the clearance", newFootprint(1, 1, 2, 40), 8.6, clearance(18.2), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := models.Block{Name: "LC01", MaxTier: 4, MaxStackHeight: tt.clearance}
+			err := checkStackHeight(tt.f, tt.height, below, block)
+			if got := errorCode(err); got != tt.wantCode {
+				t.Fatalf("checkStackHeight() code = %q (%v), want %q", got, err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestStackHeightClearance(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+		clearance := 18.0
+		updateTestBlock(t, store, func(r *dto.BlockRequest) { r.MaxStackHeight = &clearance })
+		place(t, s, "CONT0001", 1, 1, 1)
+		place(t, s, "CONT0002", 1, 1, 2)
+
+		// A third 8.6ft box would make the stack 25.8ft high.
+		err := s.PlaceContainer(placementRequest("CONT0003", 1, 1, 3))
+		wantCode(t, err, CodeStackHeightExceeded)
+		if errorKind(err) != KindRuleViolation {
+			t.Fatalf("error = %v, want a rule violation", err)
+		}
+
+		clearance = 26
+		updateTestBlock(t, store, func(r *dto.BlockRequest) { r.MaxStackHeight = &clearance })
+		place(t, s, "CONT0003", 1, 1, 3)
+	})
+}
+
+// TestSuggestionHonoursClearance checks that suggestions skip tiers above the
+// clearance while they still fit the block's tier count.
+func TestSuggestionHonoursClearance(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+		request := planRequest()
+		request.PriorityDirection = "BOTTOM_TO_TOP"
+		if _, err := NewYardManagementService(store, NewRedisService(nil)).UpdatePlan("YRD1", testPlan(t, store).ID, request); err != nil {
+			t.Fatalf("UpdatePlan: %v", err)
+		}
+		clearance := 18.0
+		updateTestBlock(t, store, func(r *dto.BlockRequest) { r.MaxStackHeight = &clearance })
+		place(t, s, "CONT0001", 1, 1, 1)
+		place(t, s, "CONT0002", 1, 1, 2)
+
+		response, err := s.GetSuggestion(suggestionRequest("CONT0003"), 1)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		wantPosition(t, response.SuggestedPosition, 1, 2, 1)
+	})
+}
+
+func TestSpecialCargoClosesStack(t *testing.T) {
+	special := func(number, containerType string, outOfGauge bool) models.Container {
+		container := box(number, 20, 1, 1, 1)
+		container.ContainerType = containerType
+		container.OutOfGauge = outOfGauge
+		return container
+	}
+
+	tests := []struct {
+		name     string
+		below    models.Container
+		wantCode string
+	}{
+		{"on a dry box", special("D1", "DRY", false), ""},
+		{"on a reefer", special("R1", ContainerTypeReefer, false), ""},
+		{"on an open-top", special("O1", ContainerTypeOpenTop, false), CodeStackOnSpecialCargo},
+		{"on a flat rack", special("F1", ContainerTypeFlatRack, false), CodeStackOnSpecialCargo},
+		{"on out-of-gauge cargo", special("G1", "DRY", true), CodeStackOnSpecialCargo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkStackSupport(newFootprint(1, 1, 2, 20), 20, []models.Container{tt.below}, false)
+			if got := errorCode(err); got != tt.wantCode {
+				t.Fatalf("checkStackSupport() code = %q (%v), want %q", got, err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCheckTopOfStack(t *testing.T) {
+	f := newFootprint(1, 1, 1, 20)
+	tests := []struct {
+		name          string
+		attrs         containerAttributes
+		occupiedAbove bool
+		wantCode      string
+	}{
+		{"dry box under a stack", containerAttributes{Type: "DRY"}, true, ""},
+		{"open-top on top", containerAttributes{Type: ContainerTypeOpenTop}, false, ""},
+		{"open-top under a stack", containerAttributes{Type: ContainerTypeOpenTop}, true, CodeSpecialCargoNotOnTop},
+		{"flat rack under a stack", containerAttributes{Type: ContainerTypeFlatRack}, true, CodeSpecialCargoNotOnTop},
+		{"out-of-gauge on top", containerAttributes{Type: "DRY", OutOfGauge: true}, false, ""},
+		{"out-of-gauge under a stack", containerAttributes{Type: "DRY", OutOfGauge: true}, true, CodeSpecialCargoNotOnTop},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorCode(checkTopOfStack(f, tt.attrs, tt.occupiedAbove)); got != tt.wantCode {
+				t.Fatalf("checkTopOfStack() code = %q, want %q", got, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestOutOfGaugeClosesStack(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+		place(t, s, "CONT0001", 1, 1, 1)
+
+		// Out-of-gauge cargo may go on top of a stack...
+		oog := placementRequest("CONT0002", 1, 1, 2)
+		oog.OutOfGauge = true
+		if err := s.PlaceContainer(oog); err != nil {
+			t.Fatalf("PlaceContainer out-of-gauge on top: %v", err)
+		}
+
+		// ...but nothing may go on it.
+		err := s.PlaceContainer(placementRequest("CONT0003", 1, 1, 3))
+		wantCode(t, err, CodeStackOnSpecialCargo)
+		if errorKind(err) != KindRuleViolation {
+			t.Fatalf("error = %v, want a rule violation", err)
+		}
+
+		request := suggestionRequest("CONT0003")
+		response, err := s.GetSuggestion(request, 20)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		if findRanked(response.RankedPositions, 1, 1, 3) != nil {
+			t.Fatalf("ranked %v, want nothing above the out-of-gauge box", response.RankedPositions)
+		}
+	})
+}
+
+func TestOpenTopClosesStack(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+		plan := planRequest()
+		plan.ContainerType = ContainerTypeOpenTop
+		plan.StartSlot, plan.EndSlot = 4, 5
+		if _, err := NewYardManagementService(store, NewRedisService(nil)).CreatePlan("YRD1", plan); err != nil {
+			t.Fatalf("CreatePlan: %v", err)
+		}
+		openTop := func(containerNumber string, slot, row, tier int) dto.PlacementRequest {
+			request := placementRequest(containerNumber, slot, row, tier)
+			request.ContainerType = ContainerTypeOpenTop
+			return request
+		}
+
+		if err := s.PlaceContainer(openTop("OPEN0001", 4, 1, 1)); err != nil {
+			t.Fatalf("PlaceContainer: %v", err)
+		}
+		wantCode(t, s.PlaceContainer(openTop("OPEN0002", 4, 1, 2)), CodeStackOnSpecialCargo)
+
+		// Nothing is stacked in this plan but the open-top, so only ground
+		// positions are offered.
+		request := suggestionRequest("OPEN0002")
+		request.ContainerType = ContainerTypeOpenTop
+		response, err := s.GetSuggestion(request, 20)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		for _, ranked := range response.RankedPositions {
+			if ranked.Tier != 1 {
+				t.Fatalf("ranked %v, want ground positions only", response.RankedPositions)
+			}
+		}
+		wantPosition(t, response.SuggestedPosition, 5, 1, 1)
+	})
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
+)
+
+// weightScale is a yard's weight-class scheme ordered from lightest to
+// heaviest. An empty scale compares exact weights.
+type weightScale []models.WeightClass
+
+func loadWeightScale(tx repositories.Store, yardID uint) (weightScale, error) {
+	classes, err := tx.WeightClasses().ListByYard(yardID)
+	if err != nil {
+		return nil, err
+	}
+	return weightScale(classes), nil
+}
+
+// classOf returns the index of the class containing weight, or -1.
+func (s weightScale) classOf(weight float64) int {
+	for i, class := range s {
+		if weight >= class.MinWeight && (class.MaxWeight == 0 || weight < class.MaxWeight) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s weightScale) className(weight float64) string {
+	if i := s.classOf(weight); i >= 0 {
+		return s[i].Name
+	}
+	return ""
+}
+
+// atLeastAsHeavy reports whether a container weighing lower may carry one
+// weighing upper. Unknown (zero) weights never count against the rule.
+func (s weightScale) atLeastAsHeavy(lower, upper float64) bool {
+	if lower == 0 || upper == 0 {
+		return true
+	}
+	lowerClass, upperClass := s.classOf(lower), s.classOf(upper)
+	if lowerClass < 0 || upperClass < 0 {
+		return lower >= upper
+	}
+	return lowerClass >= upperClass
+}
+
+// GetWeightClasses returns the weight-class scheme of a yard.
+func (s *YardManagementService) GetWeightClasses(yardName string) ([]models.WeightClass, error) {
+	yard, err := findYardByName(s.store, yardName)
+	if err != nil {
+		return nil, err
+	}
+	return loadWeightScale(s.store, yard.ID)
+}
+
+// SetWeightClasses replaces the weight-class scheme of a yard. Classes may not
+// overlap.
+func (s *YardManagementService) SetWeightClasses(yardName string, req dto.WeightClassesRequest) ([]models.WeightClass, error) {
+	var classes []models.WeightClass
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		yard, err := findYardByName(tx, yardName)
+		if err != nil {
+			return err
+		}
+
+		classes = make([]models.WeightClass, 0, len(req.Classes))
+		for _, c := range req.Classes {
+			classes = append(classes, models.WeightClass{
+				YardID:    yard.ID,
+				Name:      c.Name,
+				MinWeight: c.MinWeight,
+				MaxWeight: c.MaxWeight,
+			})
+		}
+		sort.Slice(classes, func(i, j int) bool { return classes[i].MinWeight < classes[j].MinWeight })
+
+		seen := make(map[string]bool)
+		for i, class := range classes {
+			if seen[class.Name] {
+				return newInvalidError("duplicate_weight_class",
+					fmt.Sprintf("weight class %s is listed more than once", class.Name))
+			}
+			seen[class.Name] = true
+
+			if i == 0 {
+				continue
+			}
+			prev := classes[i-1]
+			if prev.MaxWeight == 0 || prev.MaxWeight > class.MinWeight {
+				return newInvalidError("weight_class_overlap",
+					fmt.Sprintf("weight class %s overlaps weight class %s", prev.Name, class.Name))
+			}
+		}
+
+		if err := tx.WeightClasses().Replace(yard.ID, classes); err != nil {
+			return err
+		}
+
+		log.Printf("✅ Weight classes updated for yard %s: %d class(es)", yard.Name, len(classes))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return classes, nil
+}
+
+// GetWeightViolations lists stacked pairs in yard plans that request heavier
+// below lighter stacking where the upper container outweighs the lower one.
+// Such stacks can only arise from manual placements or overrides.
+func (s *YardService) GetWeightViolations(yardName string) ([]dto.WeightViolation, error) {
+	yard, err := findYardByName(s.store, yardName)
+	if err != nil {
+		return nil, err
+	}
+
+	scale, err := loadWeightScale(s.store, yard.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := weightStackedBlocks(s.store, yard.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := []dto.WeightViolation{}
+	for _, block := range blocks {
+		placed, err := s.store.Containers().ListPlaced(block.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, upper := range placed {
+			upperFp := containerFootprint(upper)
+			if owner := owningPlan(block.Plans, upperFp.StartSlot, upperFp.Row); owner == nil || !owner.StackByWeight {
+				continue
+			}
+
+			for _, lower := range placed {
+				lowerFp := containerFootprint(lower)
+				if lowerFp.Row != upperFp.Row || lowerFp.Tier != upperFp.Tier-1 ||
+					lowerFp.EndSlot() < upperFp.StartSlot || lowerFp.StartSlot > upperFp.EndSlot() {
+					continue
+				}
+				if scale.atLeastAsHeavy(lower.GrossWeight, upper.GrossWeight) {
+					continue
+				}
+
+				slot := lowerFp.StartSlot
+				if upperFp.StartSlot > slot {
+					slot = upperFp.StartSlot
+				}
+				violations = append(violations, dto.WeightViolation{
+					Block:       block.Name,
+					Slot:        slot,
+					Row:         upperFp.Row,
+					LowerTier:   lowerFp.Tier,
+					Lower:       lower.ContainerNumber,
+					LowerWeight: lower.GrossWeight,
+					LowerClass:  scale.className(lower.GrossWeight),
+					Upper:       upper.ContainerNumber,
+					UpperWeight: upper.GrossWeight,
+					UpperClass:  scale.className(upper.GrossWeight),
+				})
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// weightStackedBlocks returns the blocks of a yard in which some plan stacks
+// by weight, in id order and with all their plans.
+func weightStackedBlocks(tx repositories.Store, yardID uint) ([]models.Block, error) {
+	plans, err := tx.Plans().ListByYard(yardID)
+	if err != nil {
+		return nil, err
+	}
+
+	byBlock := make(map[uint]*models.Block)
+	stacked := make(map[uint]bool)
+	var blocks []*models.Block
+	for _, plan := range plans {
+		block, ok := byBlock[plan.BlockID]
+		if !ok {
+			block = new(models.Block)
+			*block = plan.Block
+			byBlock[plan.BlockID] = block
+			blocks = append(blocks, block)
+		}
+		block.Plans = append(block.Plans, plan)
+		stacked[plan.BlockID] = stacked[plan.BlockID] || plan.StackByWeight
+	}
+
+	var result []models.Block
+	for _, block := range blocks {
+		if stacked[block.ID] {
+			result = append(result, *block)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// owningPlan returns the highest priority plan covering (slot, row).
+func owningPlan(plans []models.YardPlan, slot, row int) *models.YardPlan {
+	var owner *models.YardPlan
+	for i := range plans {
+		if !planCovers(plans[i], slot, row) {
+			continue
+		}
+		if owner == nil || plans[i].Priority > owner.Priority ||
+			(plans[i].Priority == owner.Priority && plans[i].ID < owner.ID) {
+			owner = &plans[i]
+		}
+	}
+	return owner
+}
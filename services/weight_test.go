@@ -0,0 +1,137 @@
+package services
+
+import (
+	"testing"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/repositories"
+)
+
+// newWeightYard is the test yard with its plan stacking by weight in
+// BOTTOM_TO_TOP order, so the position above slot 1 row 1 ranks early.
+func newWeightYard(t *testing.T, store repositories.Store) *YardService {
+	t.Helper()
+	s := newTestYard(t, store, NewRedisService(nil))
+	request := planRequest()
+	request.PriorityDirection = "BOTTOM_TO_TOP"
+	request.StackByWeight = true
+	if _, err := NewYardManagementService(store, NewRedisService(nil)).UpdatePlan("YRD1", testPlan(t, store).ID, request); err != nil {
+		t.Fatalf("UpdatePlan: %v", err)
+	}
+	return s
+}
+
+func placeWeighing(t *testing.T, s *YardService, containerNumber string, weight float64, slot, row, tier int) {
+	t.Helper()
+	request := placementRequest(containerNumber, slot, row, tier)
+	request.GrossWeight = weight
+	if err := s.PlaceContainer(request); err != nil {
+		t.Fatalf("PlaceContainer(%s at %d/%d/%d): %v", containerNumber, slot, row, tier, err)
+	}
+}
+
+func weighingSuggestion(containerNumber string, weight float64) dto.SuggestionRequest {
+	request := suggestionRequest(containerNumber)
+	request.GrossWeight = weight
+	return request
+}
+
+func findRanked(ranked []dto.RankedPosition, slot, row, tier int) *dto.RankedPosition {
+	for i := range ranked {
+		if ranked[i].Slot == slot && ranked[i].Row == row && ranked[i].Tier == tier {
+			return &ranked[i]
+		}
+	}
+	return nil
+}
+
+func hasReason(position *dto.RankedPosition, reason string) bool {
+	for _, r := range position.Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWeightOrderRanking(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newWeightYard(t, store)
+		placeWeighing(t, s, "LIGHT001", 10000, 1, 1, 1)
+
+		// A heavier container is offered the position above it, but only after
+		// every position without a lighter container below.
+		heavy, err := s.GetSuggestion(weighingSuggestion("HEAVY001", 25000), 20)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		wantPosition(t, heavy.SuggestedPosition, 1, 2, 1)
+		above := findRanked(heavy.RankedPositions, 1, 1, 2)
+		if above == nil {
+			t.Fatalf("ranked %v, want 1/1/2 among them", heavy.RankedPositions)
+		}
+		if above.Score != 0.67 || !hasReason(above, "lighter container below") {
+			t.Fatalf("1/1/2 ranked as %+v, want score 0.67 for a lighter container below", *above)
+		}
+		if last := heavy.RankedPositions[len(heavy.RankedPositions)-1]; last.Position != above.Position {
+			t.Fatalf("ranked %+v last, want the penalized 1/1/2", last)
+		}
+
+		// A lighter one gets it first, in strategy order.
+		light, err := s.GetSuggestion(weighingSuggestion("LIGHT002", 8000), 3)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		wantPosition(t, light.SuggestedPosition, 1, 1, 2)
+		if top := light.RankedPositions[0]; top.Score != 1 || hasReason(&top, "lighter container below") {
+			t.Fatalf("ranked %+v first, want no weight penalty", top)
+		}
+	})
+}
+
+func TestWeightOrderRankingByClass(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newWeightYard(t, store)
+		if _, err := NewYardManagementService(store, NewRedisService(nil)).SetWeightClasses("YRD1", dto.WeightClassesRequest{
+			Classes: []dto.WeightClassRequest{
+				{Name: "LIGHT", MinWeight: 0, MaxWeight: 20000},
+				{Name: "HEAVY", MinWeight: 20000},
+			},
+		}); err != nil {
+			t.Fatalf("SetWeightClasses: %v", err)
+		}
+		placeWeighing(t, s, "LIGHT001", 10000, 1, 1, 1)
+
+		// Heavier but in the same class: no penalty.
+		response, err := s.GetSuggestion(weighingSuggestion("LIGHT002", 15000), 1)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		wantPosition(t, response.SuggestedPosition, 1, 1, 2)
+	})
+}
+
+func TestWeightViolations(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newWeightYard(t, store)
+		placeWeighing(t, s, "LIGHT001", 10000, 1, 1, 1)
+		placeWeighing(t, s, "HEAVY001", 25000, 1, 1, 2)
+		placeWeighing(t, s, "HEAVY002", 25000, 2, 1, 1)
+		placeWeighing(t, s, "LIGHT002", 10000, 2, 1, 2)
+		placeWeighing(t, s, "UNKN0001", 0, 3, 1, 1)
+		placeWeighing(t, s, "HEAVY003", 25000, 3, 1, 2)
+
+		violations, err := s.GetWeightViolations("YRD1")
+		if err != nil {
+			t.Fatalf("GetWeightViolations: %v", err)
+		}
+		want := dto.WeightViolation{
+			Block: "LC01", Slot: 1, Row: 1, LowerTier: 1,
+			Lower: "LIGHT001", LowerWeight: 10000,
+			Upper: "HEAVY001", UpperWeight: 25000,
+		}
+		if len(violations) != 1 || violations[0] != want {
+			t.Fatalf("violations = %+v, want only %+v", violations, want)
+		}
+	})
+}
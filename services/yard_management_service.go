@@ -0,0 +1,478 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
+)
+
+type YardManagementService struct {
+	store repositories.Store
+	cache *RedisService
+}
+
+func NewYardManagementService(store repositories.Store, cache *RedisService) *YardManagementService {
+	return &YardManagementService{store: store, cache: cache}
+}
+
+// invalidateYard drops the cached plans and suggestions of a yard after its
+// layout changed.
+func (s *YardManagementService) invalidateYard(yardName string) {
+	s.cache.InvalidateYardPlans(yardName)
+	s.cache.InvalidateSuggestions(yardName)
+}
+
+// Yards
+
+func (s *YardManagementService) ListYards() ([]models.Yard, error) {
+	return s.store.Yards().List()
+}
+
+func (s *YardManagementService) GetYard(name string) (*models.Yard, error) {
+	yard, err := findYardByName(s.store, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if yard.Blocks, err = s.store.Blocks().ListByYard(yard.ID); err != nil {
+		return nil, err
+	}
+	return yard, nil
+}
+
+func (s *YardManagementService) CreateYard(req dto.YardRequest) (*models.Yard, error) {
+	var yard models.Yard
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		if err := ensureYardNameFree(tx, req.Name, 0); err != nil {
+			return err
+		}
+
+		yard = models.Yard{Name: req.Name, RelaxContainerNumberCheck: req.RelaxContainerNumberCheck}
+		return tx.Yards().Create(&yard)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ Yard created: %s (ID: %d)", yard.Name, yard.ID)
+	return &yard, nil
+}
+
+func (s *YardManagementService) UpdateYard(name string, req dto.YardRequest) (*models.Yard, error) {
+	var yard *models.Yard
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		var err error
+		yard, err = findYardByName(tx, name)
+		if err != nil {
+			return err
+		}
+		if err := ensureYardNameFree(tx, req.Name, yard.ID); err != nil {
+			return err
+		}
+
+		yard.Name = req.Name
+		yard.RelaxContainerNumberCheck = req.RelaxContainerNumberCheck
+		return tx.Yards().Save(yard)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateYard(name)
+	log.Printf("✅ Yard updated: %s -> %s", name, yard.Name)
+	return yard, nil
+}
+
+func (s *YardManagementService) DeleteYard(name string) error {
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		yard, err := findYardByName(tx, name)
+		if err != nil {
+			return err
+		}
+
+		blockCount, err := tx.Blocks().CountByYard(yard.ID)
+		if err != nil {
+			return err
+		}
+		if blockCount > 0 {
+			return newConflictError("yard_has_blocks",
+				fmt.Sprintf("yard %s still has %d block(s); delete them first", yard.Name, blockCount))
+		}
+
+		if err := tx.Yards().Delete(yard.ID); err != nil {
+			return err
+		}
+
+		log.Printf("✅ Yard deleted: %s", name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.invalidateYard(name)
+	return nil
+}
+
+// Blocks
+
+func (s *YardManagementService) ListBlocks(yardName string) ([]models.Block, error) {
+	yard, err := findYardByName(s.store, yardName)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.Blocks().ListByYard(yard.ID)
+}
+
+func (s *YardManagementService) GetBlock(yardName, blockName string) (*models.Block, error) {
+	block, err := findBlockByName(s.store, yardName, blockName)
+	if err != nil {
+		return nil, err
+	}
+
+	plans, err := s.store.Plans().ListByBlock(block.ID)
+	if err != nil {
+		return nil, err
+	}
+	// The plans hang off their block; they do not repeat it.
+	for i := range plans {
+		plans[i].Block = models.Block{}
+	}
+	block.Plans = plans
+	return block, nil
+}
+
+func (s *YardManagementService) CreateBlock(yardName string, req dto.BlockRequest) (*models.Block, error) {
+	var block models.Block
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		yard, err := findYardByName(tx, yardName)
+		if err != nil {
+			return err
+		}
+		if err := ensureBlockNameFree(tx, yard.ID, req.Name, 0); err != nil {
+			return err
+		}
+
+		block = models.Block{
+			YardID:               yard.ID,
+			Name:                 req.Name,
+			MaxSlot:              req.MaxSlot,
+			MaxRow:               req.MaxRow,
+			MaxTier:              req.MaxTier,
+			AllowFortyOnTwenties: req.AllowFortyOnTwenties,
+			MaxStackHeight:       req.MaxStackHeight,
+		}
+		return tx.Blocks().Create(&block)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ Block created: %s in yard %s (ID: %d)", block.Name, yardName, block.ID)
+	return &block, nil
+}
+
+func (s *YardManagementService) UpdateBlock(yardName, blockName string, req dto.BlockRequest) (*models.Block, error) {
+	var block *models.Block
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		var err error
+		block, err = findBlockByName(tx, yardName, blockName)
+		if err != nil {
+			return err
+		}
+		if err := ensureBlockNameFree(tx, block.YardID, req.Name, block.ID); err != nil {
+			return err
+		}
+
+		// Shrinking a block must not strand plans or placed containers outside it.
+		plans, err := tx.Plans().ListByBlock(block.ID)
+		if err != nil {
+			return err
+		}
+		for _, plan := range plans {
+			if plan.EndSlot > req.MaxSlot || plan.EndRow > req.MaxRow {
+				return newConflictError("plan_outside_block",
+					fmt.Sprintf("yard plan %d (slots %d-%d, rows %d-%d) would fall outside the resized block",
+						plan.ID, plan.StartSlot, plan.EndSlot, plan.StartRow, plan.EndRow))
+			}
+		}
+
+		placed, err := tx.Containers().ListPlaced(block.ID)
+		if err != nil {
+			return err
+		}
+		for _, container := range placed {
+			f := containerFootprint(container)
+			if f.EndSlot() > req.MaxSlot || f.Row > req.MaxRow || f.Tier > req.MaxTier {
+				return newConflictError("container_outside_block",
+					fmt.Sprintf("container %s would fall outside the resized block", container.ContainerNumber))
+			}
+		}
+
+		block.Name = req.Name
+		block.MaxSlot = req.MaxSlot
+		block.MaxRow = req.MaxRow
+		block.MaxTier = req.MaxTier
+		block.AllowFortyOnTwenties = req.AllowFortyOnTwenties
+		block.MaxStackHeight = req.MaxStackHeight
+		return tx.Blocks().Save(block)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateYard(yardName)
+	s.cache.DropBlockOccupancy(block.ID)
+	log.Printf("✅ Block updated: %s -> %s in yard %s", blockName, block.Name, yardName)
+	return block, nil
+}
+
+func (s *YardManagementService) DeleteBlock(yardName, blockName string) error {
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		block, err := findBlockByName(tx, yardName, blockName)
+		if err != nil {
+			return err
+		}
+
+		containerCount, err := tx.Containers().CountByBlock(block.ID)
+		if err != nil {
+			return err
+		}
+		if containerCount > 0 {
+			return newConflictError("block_has_containers",
+				fmt.Sprintf("block %s still has %d container record(s)", block.Name, containerCount))
+		}
+
+		reservations, err := tx.Suggestions().ListActiveByBlock(block.ID)
+		if err != nil {
+			return err
+		}
+		if len(reservations) > 0 {
+			return newConflictError("block_has_reservations",
+				fmt.Sprintf("block %s still has %d active reservation(s); cancel them or let them expire", block.Name, len(reservations)))
+		}
+
+		// Expired suggestions only remember attributes; they go with the block.
+		if err := tx.Suggestions().DeleteByBlock(block.ID); err != nil {
+			return err
+		}
+		if err := tx.Plans().DeleteByBlock(block.ID); err != nil {
+			return err
+		}
+		if err := tx.ReeferPlugs().Replace(block.ID, nil); err != nil {
+			return err
+		}
+		if err := tx.Blocks().Delete(block.ID); err != nil {
+			return err
+		}
+
+		log.Printf("✅ Block deleted: %s from yard %s", blockName, yardName)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.invalidateYard(yardName)
+	return nil
+}
+
+// Yard plans
+
+func (s *YardManagementService) ListPlans(yardName string) ([]models.YardPlan, error) {
+	yard, err := findYardByName(s.store, yardName)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.Plans().ListByYard(yard.ID)
+}
+
+func (s *YardManagementService) GetPlan(yardName string, planID uint) (*models.YardPlan, error) {
+	return findPlanInYard(s.store, yardName, planID)
+}
+
+func (s *YardManagementService) CreatePlan(yardName string, req dto.YardPlanRequest) (*models.YardPlan, error) {
+	var plan models.YardPlan
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		block, err := findBlockByName(tx, yardName, req.Block)
+		if err != nil {
+			return err
+		}
+		if err := req.CheckWithinBlock(block.MaxSlot, block.MaxRow); err != nil {
+			return newInvalidError("plan_outside_block", err.Error())
+		}
+		if err := checkPlacementStrategy(req.PriorityDirection); err != nil {
+			return err
+		}
+
+		plan = models.YardPlan{BlockID: block.ID}
+		applyPlanRequest(&plan, req)
+		if err := checkPlanOverlap(tx, plan, block.Name); err != nil {
+			return err
+		}
+		if err := tx.Plans().Create(&plan); err != nil {
+			return err
+		}
+
+		plan.Block = *block
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateYard(yardName)
+	log.Printf("✅ Yard plan created: ID=%d, Block=%s, Slots=%d-%d, Rows=%d-%d",
+		plan.ID, req.Block, plan.StartSlot, plan.EndSlot, plan.StartRow, plan.EndRow)
+	return &plan, nil
+}
+
+func (s *YardManagementService) UpdatePlan(yardName string, planID uint, req dto.YardPlanRequest) (*models.YardPlan, error) {
+	var plan *models.YardPlan
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		var err error
+		plan, err = findPlanInYard(tx, yardName, planID)
+		if err != nil {
+			return err
+		}
+
+		block, err := findBlockByName(tx, yardName, req.Block)
+		if err != nil {
+			return err
+		}
+		if err := req.CheckWithinBlock(block.MaxSlot, block.MaxRow); err != nil {
+			return newInvalidError("plan_outside_block", err.Error())
+		}
+		if err := checkPlacementStrategy(req.PriorityDirection); err != nil {
+			return err
+		}
+
+		plan.BlockID = block.ID
+		plan.Block = *block
+		applyPlanRequest(plan, req)
+		if err := checkPlanOverlap(tx, *plan, block.Name); err != nil {
+			return err
+		}
+		return tx.Plans().Save(plan)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateYard(yardName)
+	log.Printf("✅ Yard plan updated: ID=%d, Block=%s, Slots=%d-%d, Rows=%d-%d",
+		plan.ID, req.Block, plan.StartSlot, plan.EndSlot, plan.StartRow, plan.EndRow)
+	return plan, nil
+}
+
+func (s *YardManagementService) DeletePlan(yardName string, planID uint) error {
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		plan, err := findPlanInYard(tx, yardName, planID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Plans().Delete(plan.ID); err != nil {
+			return err
+		}
+
+		log.Printf("✅ Yard plan deleted: ID=%d from yard %s", planID, yardName)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.invalidateYard(yardName)
+	return nil
+}
+
+func checkPlacementStrategy(name string) error {
+	if _, ok := LookupPlacementStrategy(name); ok {
+		return nil
+	}
+	return newInvalidError("unknown_placement_strategy",
+		fmt.Sprintf("priority_direction must be one of: %s", strings.Join(PlacementStrategyNames(), ", ")))
+}
+
+func applyPlanRequest(plan *models.YardPlan, req dto.YardPlanRequest) {
+	plan.ContainerSize = req.ContainerSize
+	plan.ContainerHeight = req.ContainerHeight
+	plan.ContainerType = req.ContainerType
+	plan.StartSlot = req.StartSlot
+	plan.EndSlot = req.EndSlot
+	plan.StartRow = req.StartRow
+	plan.EndRow = req.EndRow
+	plan.PriorityDirection = req.PriorityDirection
+	plan.Priority = req.Priority
+	plan.AllowOverlap = req.AllowOverlap
+	plan.StackByWeight = req.StackByWeight
+}
+
+func findYardByName(tx repositories.Store, name string) (*models.Yard, error) {
+	yard, err := tx.Yards().FindByName(name)
+	if err != nil {
+		return nil, yardLookupError(err)
+	}
+	return yard, nil
+}
+
+func findBlockByName(tx repositories.Store, yardName, blockName string) (*models.Block, error) {
+	block, err := tx.Blocks().FindByName(yardName, blockName)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, newNotFoundError("block_not_found", "block not found in specified yard")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func findPlanInYard(tx repositories.Store, yardName string, planID uint) (*models.YardPlan, error) {
+	plan, err := tx.Plans().FindInYard(yardName, planID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, newNotFoundError("plan_not_found", "yard plan not found in specified yard")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func yardLookupError(err error) error {
+	if errors.Is(err, repositories.ErrNotFound) {
+		return newNotFoundError("yard_not_found", "yard not found")
+	}
+	return err
+}
+
+func ensureYardNameFree(tx repositories.Store, name string, selfID uint) error {
+	existing, err := tx.Yards().FindByName(name)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != selfID {
+		return newConflictError("yard_exists", fmt.Sprintf("yard %s already exists", name))
+	}
+	return nil
+}
+
+func ensureBlockNameFree(tx repositories.Store, yardID uint, name string, selfID uint) error {
+	blocks, err := tx.Blocks().ListByYard(yardID)
+	if err != nil {
+		return err
+	}
+	for _, existing := range blocks {
+		if existing.Name == name && existing.ID != selfID {
+			return newConflictError("block_exists", fmt.Sprintf("block %s already exists in this yard", name))
+		}
+	}
+	return nil
+}
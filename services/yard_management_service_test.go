@@ -0,0 +1,38 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"backend_yard_planning_system/repositories"
+)
+
+func TestDeleteBlockWithReservations(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+		management := NewYardManagementService(store, NewRedisService(nil))
+		if _, err := s.GetSuggestion(suggestionRequest("CONT0001"), 1); err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+
+		err := management.DeleteBlock("YRD1", "LC01")
+		wantCode(t, err, "block_has_reservations")
+		if errorKind(err) != KindConflict {
+			t.Fatalf("error = %v, want a conflict", err)
+		}
+
+		// The expired suggestion goes with the block.
+		if err := s.CancelReservation("YRD1", "CONT0001"); err != nil {
+			t.Fatalf("CancelReservation: %v", err)
+		}
+		if err := management.DeleteBlock("YRD1", "LC01"); err != nil {
+			t.Fatalf("DeleteBlock: %v", err)
+		}
+		if _, err := store.Suggestions().FindByContainer("CONT0001"); !errors.Is(err, repositories.ErrNotFound) {
+			t.Fatalf("suggestion after deleting its block: %v, want it dropped", err)
+		}
+		if _, err := management.GetBlock("YRD1", "LC01"); errorKind(err) != KindNotFound {
+			t.Fatalf("GetBlock after delete: %v, want not found", err)
+		}
+	})
+}
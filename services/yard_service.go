@@ -5,52 +5,106 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"slices"
+	"sort"
 	"time"
 
-	"backend_yard_planning_system/database"
+	"backend_yard_planning_system/config"
 	"backend_yard_planning_system/dto"
 	"backend_yard_planning_system/models"
+	"backend_yard_planning_system/repositories"
 
-	"gorm.io/gorm"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	CodeContainerAlreadyPlaced = "container_already_placed"
+	CodeNoMatchingPlan         = "no_matching_plan"
+	CodeNoAvailablePosition    = "no_available_position"
+)
+
+var (
+	errNoMatchingPlan = errors.New("no matching yard plan")
+	errNoFreePosition = errors.New("no available position found in the planned area")
 )
 
 type YardService struct {
-	db *gorm.DB
+	store       repositories.Store
+	cache       *RedisService
+	suggestions singleflight.Group
 }
 
-func NewYardService() *YardService {
-	return &YardService{db: database.DB}
+func NewYardService(store repositories.Store, cache *RedisService) *YardService {
+	return &YardService{store: store, cache: cache}
 }
 
-func (s *YardService) GetSuggestion(req dto.SuggestionRequest) (*dto.SuggestionResponse, error) {
+// GetSuggestion returns the best position for a container and reserves it for
+// the container until the reservation TTL passes, so concurrent suggestions
+// for other containers skip it. With limit above 1 the response also ranks up
+// to limit positions, best first, across the matching plans in order of
+// precedence. Only the best position is checked against the database; the
+// others are ranked from the cached occupancy and checked when placed.
+func (s *YardService) GetSuggestion(req dto.SuggestionRequest, limit int) (*dto.SuggestionResponse, error) {
+	if limit < 1 {
+		limit = 1
+	}
+
+	// Plain single suggestions start from a ranking shared by their container
+	// class, computed before taking the yard lock.
+	var shared []cachedPosition
+	if attrs := suggestionAttributes(req); limit == 1 && cacheableSuggestion(attrs) {
+		shared = s.sharedSuggestions(req.Yard, attrs)
+	}
+
+	var response *dto.SuggestionResponse
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		var err error
+		response, err = s.suggest(tx, req, limit, shared)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func suggestionAttributes(req dto.SuggestionRequest) containerAttributes {
+	return containerAttributes{
+		Size:        req.ContainerSize,
+		Height:      req.ContainerHeight,
+		Type:        req.ContainerType,
+		SizeType:    req.SizeType,
+		OutOfGauge:  req.OutOfGauge,
+		GrossWeight: req.GrossWeight,
+
+		ExpectedDeparture: req.ExpectedDeparture,
+		VesselVoyage:      req.VesselVoyage,
+	}
+}
+
+func (s *YardService) suggest(tx repositories.Store, req dto.SuggestionRequest, limit int, shared []cachedPosition) (*dto.SuggestionResponse, error) {
 	log.Printf("🔍 Searching yard plan for: Yard=%s, Size=%d, Height=%.1f, Type=%s",
 		req.Yard, req.ContainerSize, req.ContainerHeight, req.ContainerType)
 
-	var yard models.Yard
-	if err := s.db.Where("name = ?", req.Yard).First(&yard).Error; err != nil {
+	// Locking the yard serialises suggestions within it, so two requests cannot
+	// reserve the same position.
+	yard, err := tx.Yards().LockByName(req.Yard)
+	if err != nil {
 		log.Printf("❌ Yard not found: %s", req.Yard)
-		return nil, errors.New("yard not found")
+		return nil, yardLookupError(err)
 	}
 	log.Printf("✅ Found yard: %s (ID: %d)", yard.Name, yard.ID)
 
-	var yardPlan models.YardPlan
-	query := s.db.Joins("JOIN blocks ON blocks.id = yard_plans.block_id").
-		Where("blocks.yard_id = ?", yard.ID).
-		Where("container_size = ?", req.ContainerSize).
-		Where("container_type = ?", req.ContainerType).
-		Where("ABS(container_height - ?) < 0.01", req.ContainerHeight).
-		Preload("Block")
-
-	err := query.First(&yardPlan).Error
+	attrs := suggestionAttributes(req)
 
+	yardPlans, err := matchingPlans(tx, yard.ID, attrs)
 	if err != nil {
+		return nil, err
+	}
+	if len(yardPlans) == 0 {
 		log.Printf("❌ No exact match found. Error: %v", err)
 
-		var allPlans []models.YardPlan
-		s.db.Joins("JOIN blocks ON blocks.id = yard_plans.block_id").
-			Where("blocks.yard_id = ?", yard.ID).
-			Preload("Block").
-			Find(&allPlans)
+		allPlans, _ := tx.Plans().ListByYard(yard.ID)
 
 		log.Printf("📋 All plans in database for yard %s:", req.Yard)
 		for i, plan := range allPlans {
@@ -65,86 +119,262 @@ func (s *YardService) GetSuggestion(req dto.SuggestionRequest) (*dto.SuggestionR
 				matchSize, matchHeight, matchType)
 		}
 
-		return nil, fmt.Errorf("no suitable yard plan found. Check server logs for details.")
+		return nil, &ServiceError{
+			Kind: KindRuleViolation,
+			Code: CodeNoMatchingPlan,
+			Message: fmt.Sprintf("no yard plan in yard %s takes %dft %.1f %s containers",
+				req.Yard, req.ContainerSize, req.ContainerHeight, req.ContainerType),
+		}
 	}
 
-	log.Printf("✅ Found matching yard plan: Block=%s, Slots=%d-%d, Rows=%d-%d",
-		yardPlan.Block.Name, yardPlan.StartSlot, yardPlan.EndSlot, yardPlan.StartRow, yardPlan.EndRow)
-
-	var existingContainer models.Container
-	if err := s.db.Where("container_number = ? AND is_placed = ?", req.ContainerNumber, true).First(&existingContainer).Error; err == nil {
+	if existing, err := tx.Containers().FindByNumber(req.ContainerNumber); err == nil && existing.IsPlaced {
 		log.Printf("❌ Container already placed: %s", req.ContainerNumber)
-		return nil, errors.New("container is already placed in the yard")
+		return nil, newConflictError(CodeContainerAlreadyPlaced, "container is already placed in the yard")
+	}
+
+	// The shared ranking treats every reservation as taken, including the one
+	// a repeated suggestion for the container should get back.
+	if len(shared) > 0 {
+		if own, err := tx.Suggestions().FindByContainer(req.ContainerNumber); err == nil && own.ExpiresAt.After(time.Now()) {
+			shared = nil
+		}
 	}
 
-	position, err := s.findAvailablePosition(yardPlan, req.ContainerSize)
+	scale, err := loadWeightScale(tx, yard.ID)
 	if err != nil {
-		log.Printf("❌ No available position: %v", err)
-		return nil, fmt.Errorf("no available position found: %v", err)
+		return nil, err
 	}
 
-	log.Printf("🎯 Suggested position: Block=%s, Slot=%d, Row=%d, Tier=%d",
-		yardPlan.Block.Name, position.Slot, position.Row, position.Tier)
+	var ranked []rankedPosition
+	hit, err := firstSharedPosition(tx, yardPlans, req.ContainerNumber, shared)
+	if err != nil {
+		return nil, err
+	}
+	if hit != nil {
+		ranked = []rankedPosition{*hit}
+	}
 
-	return &dto.SuggestionResponse{
+	// The ranking reads the cached occupancy, which may lag behind the
+	// database. Only the position picked is checked against it; when that
+	// fails, its block is ranked again from the database.
+	reloaded := make(map[uint]bool)
+	for {
+		if ranked == nil {
+			ranked, err = s.rankAcrossPlans(tx, yardPlans, attrs, req.ContainerNumber, nil, scale, limit)
+			if err != nil {
+				log.Printf("❌ No available position: %v", err)
+				return nil, noPositionError(err)
+			}
+		}
+
+		best := ranked[0]
+		fp := newFootprint(best.Slot, best.Row, best.Tier, attrs.Size)
+		err := checkPosition(tx, best.plan.Block, fp, attrs, req.ContainerNumber, false)
+		if err == nil {
+			break
+		}
+		if reloaded[best.plan.BlockID] {
+			return nil, noPositionError(err)
+		}
+		log.Printf("⚠️ Cached occupancy of block %s is stale, ranking it again from the database: %v",
+			best.plan.Block.Name, err)
+		s.cache.DropBlockOccupancy(best.plan.BlockID)
+		reloaded[best.plan.BlockID] = true
+		ranked = nil
+	}
+
+	best := ranked[0]
+	yardPlan, position := best.plan, best.position
+	log.Printf("🎯 Suggested position: Block=%s, Slot=%d, Row=%d, Tier=%d (%d ranked)",
+		yardPlan.Block.Name, position.Slot, position.Row, position.Tier, len(ranked))
+
+	expiresAt := time.Now().Add(config.SuggestionReservationTTL())
+	suggestion := models.Suggestion{
+		ContainerNumber: req.ContainerNumber,
+		YardID:          yard.ID,
+		BlockID:         yardPlan.BlockID,
+		YardPlanID:      yardPlan.ID,
+		ContainerSize:   req.ContainerSize,
+		ContainerHeight: req.ContainerHeight,
+		ContainerType:   req.ContainerType,
+		SizeType:        req.SizeType,
+		OutOfGauge:      req.OutOfGauge,
+		GrossWeight:     req.GrossWeight,
+
+		ExpectedDeparture: req.ExpectedDeparture,
+		VesselVoyage:      req.VesselVoyage,
+		Slot:              position.Slot,
+		SlotSpan:          slotSpanForSize(req.ContainerSize),
+		Row:               position.Row,
+		Tier:              position.Tier,
+		ExpiresAt:         expiresAt,
+	}
+	if err := tx.Suggestions().Upsert(&suggestion); err != nil {
+		log.Printf("❌ Failed to reserve suggestion for %s: %v", req.ContainerNumber, err)
+		return nil, err
+	}
+	log.Printf("🔒 Reserved Block=%s, Slot=%d, Row=%d, Tier=%d for %s until %s",
+		yardPlan.Block.Name, position.Slot, position.Row, position.Tier, req.ContainerNumber, expiresAt.Format(time.RFC3339))
+
+	suggested := newFootprint(position.Slot, position.Row, position.Tier, req.ContainerSize)
+	if err := recordEvent(tx, containerEvent(EventSuggested, req.ContainerNumber, yard.ID,
+		nil, at(yardPlan.Block.Name, suggested), req.User, "")); err != nil {
+		return nil, err
+	}
+
+	response := &dto.SuggestionResponse{
 		SuggestedPosition: dto.Position{
 			Block: yardPlan.Block.Name,
 			Slot:  position.Slot,
 			Row:   position.Row,
 			Tier:  position.Tier,
 		},
-	}, nil
+		ReservedUntil: &expiresAt,
+	}
+	if limit > 1 {
+		response.RankedPositions = make([]dto.RankedPosition, 0, len(ranked))
+		for _, r := range ranked {
+			response.RankedPositions = append(response.RankedPositions, dto.RankedPosition{
+				Position: dto.Position{
+					Block: r.plan.Block.Name,
+					Slot:  r.Slot,
+					Row:   r.Row,
+					Tier:  r.Tier,
+				},
+				YardPlanID: r.plan.ID,
+				Score:      r.score(),
+				Reasons:    r.reasons,
+			})
+		}
+	}
+	return response, nil
 }
 
-func (s *YardService) PlaceContainer(req dto.PlacementRequest) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		var block models.Block
-		err := tx.Joins("JOIN yards ON yards.id = blocks.yard_id").
-			Where("yards.name = ? AND blocks.name = ?", req.Yard, req.Block).
-			First(&block).Error
+// noPositionError reports that a suggestion found no position, because every
+// plan is full or its best position failed the checks. Store errors pass
+// through unchanged.
+func noPositionError(err error) error {
+	var serviceErr *ServiceError
+	if !errors.Is(err, errNoFreePosition) && !errors.Is(err, errNoMatchingPlan) && !errors.As(err, &serviceErr) {
+		return err
+	}
+	return &ServiceError{
+		Kind:    KindRuleViolation,
+		Code:    CodeNoAvailablePosition,
+		Message: fmt.Sprintf("no available position found: %v", err),
+	}
+}
+
+// matchingPlans returns the yard plans for containers with attrs in order of
+// precedence; where plans overlap the higher priority plan owns the shared
+// cells.
+func matchingPlans(tx repositories.Store, yardID uint, attrs containerAttributes) ([]models.YardPlan, error) {
+	return tx.Plans().ListMatching(yardID, attrs.Size, attrs.Height, attrs.Type)
+}
+
+// rankAcrossPlans ranks up to limit positions for containerNumber, trying the
+// plans in order. excluded holds extra footprints per block to treat as taken.
+func (s *YardService) rankAcrossPlans(tx repositories.Store, yardPlans []models.YardPlan, attrs containerAttributes, containerNumber string, excluded map[uint][]footprint, scale weightScale, limit int) ([]rankedPosition, error) {
+	var ranked []rankedPosition
+	err := errNoMatchingPlan
+	for _, plan := range yardPlans {
+		log.Printf("✅ Found matching yard plan: Block=%s, Slots=%d-%d, Rows=%d-%d, Priority=%d",
+			plan.Block.Name, plan.StartSlot, plan.EndSlot, plan.StartRow, plan.EndRow, plan.Priority)
+
+		var shadows []models.YardPlan
+		if plan.AllowOverlap {
+			blockPlans, _ := tx.Plans().ListByBlock(plan.BlockID)
+			shadows = shadowingPlans(plan, blockPlans)
+		}
+
+		reserved, rankErr := reservedFootprints(tx, plan.BlockID, containerNumber)
+		if rankErr != nil {
+			return nil, rankErr
+		}
+		reserved = append(reserved, excluded[plan.BlockID]...)
+
+		positions, rankErr := s.rankPositions(tx, plan, attrs, shadows, reserved, scale, limit-len(ranked))
+		if rankErr != nil {
+			err = rankErr
+			log.Printf("ℹ️ Plan %d has no free position, trying next plan", plan.ID)
+			continue
+		}
+		ranked = append(ranked, positions...)
+		if len(ranked) >= limit {
+			break
+		}
+	}
+
+	if len(ranked) == 0 {
+		return nil, err
+	}
+	return ranked, nil
+}
 
+func (s *YardService) PlaceContainer(req dto.PlacementRequest) error {
+	var changes occupancyChanges
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		block, err := findBlockByName(tx, req.Yard, req.Block)
 		if err != nil {
 			log.Printf("❌ Block not found: Yard=%s, Block=%s, Error: %v", req.Yard, req.Block, err)
-			return errors.New("block not found in specified yard")
+			return err
+		}
+
+		// Locking the block serialises placements into it, so the occupancy
+		// and stacking checks below cannot race with another placement.
+		if err := tx.Blocks().Lock(block.ID); err != nil {
+			return err
 		}
 
 		log.Printf("✅ Found block: %s (ID: %d) in yard: %s", block.Name, block.ID, req.Yard)
 
-		if req.Slot < 1 || req.Slot > block.MaxSlot ||
-			req.Row < 1 || req.Row > block.MaxRow ||
-			req.Tier < 1 || req.Tier > block.MaxTier {
-			log.Printf("❌ Invalid position: Slot=%d/%d, Row=%d/%d, Tier=%d/%d",
-				req.Slot, block.MaxSlot, req.Row, block.MaxRow, req.Tier, block.MaxTier)
-			return errors.New("position exceeds block capacity")
+		existingContainer, err := tx.Containers().FindByNumber(req.ContainerNumber)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return err
+		}
+		// Relocating a placed container must go through the move, which checks
+		// for containers stacked on it and records where it came from.
+		if existingContainer != nil && existingContainer.IsPlaced {
+			log.Printf("❌ Container already placed: %s", req.ContainerNumber)
+			return newConflictError(CodeContainerAlreadyPlaced,
+				fmt.Sprintf("container %s is already placed; use /api/move to relocate it", req.ContainerNumber))
 		}
 
-		var occupiedContainer models.Container
-		if err := tx.Where("block_id = ? AND slot = ? AND row = ? AND tier = ? AND is_placed = ?",
-			block.ID, req.Slot, req.Row, req.Tier, true).First(&occupiedContainer).Error; err == nil {
-			log.Printf("❌ Position occupied: Block=%s, Slot=%d, Row=%d, Tier=%d by Container=%s",
-				block.Name, req.Slot, req.Row, req.Tier, occupiedContainer.ContainerNumber)
-			return errors.New("position is already occupied")
+		attrs, err := resolveContainerAttributes(tx, req, block.YardID)
+		if err != nil {
+			return err
 		}
 
-		log.Printf("✅ Position available: Block=%s, Slot=%d, Row=%d, Tier=%d",
-			block.Name, req.Slot, req.Row, req.Tier)
+		fp := newFootprint(req.Slot, req.Row, req.Tier, attrs.Size)
+		if err := checkPosition(tx, *block, fp, attrs, req.ContainerNumber, req.SupervisorOverride); err != nil {
+			return err
+		}
 
-		var existingContainer models.Container
-		if err := tx.Where("container_number = ?", req.ContainerNumber).First(&existingContainer).Error; err == nil {
+		if existingContainer != nil {
 			log.Printf("ℹ️ Container exists, updating: %s", req.ContainerNumber)
 
 			existingContainer.BlockID = block.ID
+			existingContainer.ContainerSize = attrs.Size
+			existingContainer.ContainerHeight = attrs.Height
+			existingContainer.ContainerType = attrs.Type
+			existingContainer.SizeType = attrs.SizeType
+			existingContainer.OutOfGauge = attrs.OutOfGauge
+			existingContainer.GrossWeight = attrs.GrossWeight
+			existingContainer.ExpectedDeparture = attrs.ExpectedDeparture
+			existingContainer.VesselVoyage = attrs.VesselVoyage
 			existingContainer.Slot = req.Slot
+			existingContainer.SlotSpan = fp.Span
 			existingContainer.Row = req.Row
 			existingContainer.Tier = req.Tier
 			existingContainer.IsPlaced = true
 			existingContainer.PlacedAt = time.Now()
 			existingContainer.PickedUpAt = nil
 
-			if err := tx.Save(&existingContainer).Error; err != nil {
+			if err := tx.Containers().Save(existingContainer); err != nil {
 				log.Printf("❌ Failed to update container: %v", err)
-				return err
+				return placementWriteError(err)
 			}
+			changes.placed(*block, *existingContainer)
 
 			log.Printf("✅ Container updated and placed: %s", req.ContainerNumber)
 		} else {
@@ -153,43 +383,174 @@ func (s *YardService) PlaceContainer(req dto.PlacementRequest) error {
 			container := models.Container{
 				ContainerNumber: req.ContainerNumber,
 				BlockID:         block.ID,
-				ContainerSize:   20,    // Default value, bisa disesuaikan
-				ContainerHeight: 8.6,   // Default value, bisa disesuaikan
-				ContainerType:   "DRY", // Default value, bisa disesuaikan
-				Slot:            req.Slot,
-				Row:             req.Row,
-				Tier:            req.Tier,
-				IsPlaced:        true,
-				PlacedAt:        time.Now(),
+				ContainerSize:   attrs.Size,
+				ContainerHeight: attrs.Height,
+				ContainerType:   attrs.Type,
+				SizeType:        attrs.SizeType,
+				OutOfGauge:      attrs.OutOfGauge,
+				GrossWeight:     attrs.GrossWeight,
+
+				ExpectedDeparture: attrs.ExpectedDeparture,
+				VesselVoyage:      attrs.VesselVoyage,
+				Slot:              req.Slot,
+				SlotSpan:          fp.Span,
+				Row:               req.Row,
+				Tier:              req.Tier,
+				IsPlaced:          true,
+				PlacedAt:          time.Now(),
 			}
 
-			if err := tx.Create(&container).Error; err != nil {
+			if err := tx.Containers().Create(&container); err != nil {
 				log.Printf("❌ Failed to create container: %v", err)
-				return err
+				return placementWriteError(err)
 			}
+			changes.placed(*block, container)
 
 			log.Printf("✅ New container created and placed: %s (Size: %d, Height: %.1f, Type: %s)",
 				req.ContainerNumber, container.ContainerSize, container.ContainerHeight, container.ContainerType)
 		}
 
+		// The placement consumes the container's suggestion and its reservation.
+		if err := tx.Suggestions().DeleteByContainer(req.ContainerNumber); err != nil {
+			return err
+		}
+
+		reason := ""
+		if req.SupervisorOverride {
+			reason = ReasonSupervisorOverride
+		}
+		if err := recordEvent(tx, containerEvent(EventPlaced, req.ContainerNumber, block.YardID,
+			nil, at(block.Name, fp), req.User, reason)); err != nil {
+			return err
+		}
+
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	s.applyOccupancy(changes)
+	return nil
 }
 
-func (s *YardService) PickupContainer(req dto.PickupRequest) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		var container models.Container
+// checkPosition applies the capacity, occupancy, reservation and stacking
+// rules to putting a container with attrs at fp. override lets a supervisor
+// bypass reservations and yard plans, but never physical rules.
+func checkPosition(tx repositories.Store, block models.Block, fp footprint, attrs containerAttributes, containerNumber string, override bool) error {
+	if fp.StartSlot < 1 || fp.EndSlot() > block.MaxSlot ||
+		fp.Row < 1 || fp.Row > block.MaxRow ||
+		fp.Tier < 1 || fp.Tier > block.MaxTier {
+		log.Printf("❌ Invalid position: Slot=%d-%d/%d, Row=%d/%d, Tier=%d/%d",
+			fp.StartSlot, fp.EndSlot(), block.MaxSlot, fp.Row, block.MaxRow, fp.Tier, block.MaxTier)
+		return newInvalidError("position_outside_block", "position exceeds block capacity")
+	}
 
-		err := tx.Joins("JOIN blocks ON blocks.id = containers.block_id").
-			Joins("JOIN yards ON yards.id = blocks.yard_id").
-			Where("containers.container_number = ? AND yards.name = ?",
-				req.ContainerNumber, req.Yard).
-			First(&container).Error
+	row, err := tx.Containers().ListPlacedInRow(block.ID, fp.Row)
+	if err != nil {
+		return err
+	}
 
+	if occupiedContainer := findOccupant(row, fp); occupiedContainer != nil {
+		log.Printf("❌ Position occupied: Block=%s, Slot=%d-%d, Row=%d, Tier=%d by Container=%s",
+			block.Name, fp.StartSlot, fp.EndSlot(), fp.Row, fp.Tier, occupiedContainer.ContainerNumber)
+		return newConflictError(CodePositionOccupied, "position is already occupied")
+	}
+
+	if err := checkReservation(tx, block.ID, fp, containerNumber); err != nil {
+		if !override {
+			log.Printf("❌ Position reserved: Block=%s, Slot=%d-%d, Row=%d, Tier=%d: %v",
+				block.Name, fp.StartSlot, fp.EndSlot(), fp.Row, fp.Tier, err)
+			return err
+		}
+		log.Printf("⚠️ Supervisor override for %s: %v", containerNumber, err)
+	}
+
+	log.Printf("✅ Position available: Block=%s, Slot=%d-%d, Row=%d, Tier=%d",
+		block.Name, fp.StartSlot, fp.EndSlot(), fp.Row, fp.Tier)
+
+	if fp.Tier > 1 {
+		supporters := stackAt(row, fp, func(tier int) bool { return tier == fp.Tier-1 })
+		if err := checkStackSupport(fp, attrs.Size, supporters, block.AllowFortyOnTwenties); err != nil {
+			log.Printf("❌ Stacking rule violated for %s: %v", containerNumber, err)
+			return err
+		}
+	}
+
+	if attrs.closesStack() {
+		above := stackAt(row, fp, func(tier int) bool { return tier > fp.Tier })
+		if err := checkTopOfStack(fp, attrs, len(above) > 0); err != nil {
+			log.Printf("❌ Stacking rule violated for %s: %v", containerNumber, err)
+			return err
+		}
+	}
+
+	if attrs.Type == ContainerTypeReefer {
+		plugs, err := loadReeferPlugs(tx, block.ID)
 		if err != nil {
-			log.Printf(" Container not found: Number=%s, Yard=%s, Error: %v",
-				req.ContainerNumber, req.Yard, err)
-			return errors.New("container not found in specified yard")
+			return err
+		}
+		if err := checkReeferPower(fp, attrs.Type, plugs, block.Name); err != nil {
+			log.Printf("❌ Reefer placement rejected for %s: %v", containerNumber, err)
+			return err
+		}
+	}
+
+	if block.MaxStackHeight != nil {
+		stack := stackAt(row, fp, func(tier int) bool { return tier < fp.Tier })
+		if err := checkStackHeight(fp, attrs.Height, stack, block); err != nil {
+			log.Printf("❌ Stack height exceeded for %s: %v", containerNumber, err)
+			return err
+		}
+	}
+
+	if err := checkPlanMatch(tx, block, fp, attrs); err != nil {
+		if !override {
+			return err
+		}
+		log.Printf("⚠️ Supervisor override for %s: %v", containerNumber, err)
+	}
+
+	return nil
+}
+
+// placementWriteError turns a unique index violation from a concurrent
+// placement into a conflict. The index only catches two containers starting
+// on the same cell; one covering the second slot of a 40ft is kept out by the
+// block lock.
+func placementWriteError(err error) error {
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return newConflictError(CodePositionOccupied, "position was taken by a concurrent placement")
+	}
+	return err
+}
+
+// PickupContainer lifts a container out of the yard. Containers stacked on it
+// block the pickup unless the request plans or executes the rehandles that
+// clear them.
+func (s *YardService) PickupContainer(req dto.PickupRequest) (*dto.PickupResponse, error) {
+	var response *dto.PickupResponse
+	var changes occupancyChanges
+	err := s.store.Transaction(func(tx repositories.Store) error {
+		container, err := tx.Containers().FindInYard(req.ContainerNumber, req.Yard)
+		if errors.Is(err, repositories.ErrNotFound) {
+			log.Printf(" Container not found: Number=%s, Yard=%s", req.ContainerNumber, req.Yard)
+			return newNotFoundError("container_not_found", "container not found in specified yard")
+		}
+		if err != nil {
+			return err
+		}
+
+		locks, err := pickupLocks(tx, req.Yard, container.BlockID, req.ExecuteRehandles)
+		if err != nil {
+			return err
+		}
+		if err := tx.Blocks().Lock(locks...); err != nil {
+			return err
+		}
+		// Read the container again under the locks in case it moved meanwhile.
+		if container, err = tx.Containers().FindInYard(req.ContainerNumber, req.Yard); err != nil {
+			return err
 		}
 
 		log.Printf("✅ Found container: %s in yard: %s (Placed: %t)",
@@ -197,21 +558,167 @@ func (s *YardService) PickupContainer(req dto.PickupRequest) error {
 
 		if !container.IsPlaced {
 			log.Printf(" Container not placed: %s", req.ContainerNumber)
-			return errors.New("container is not currently placed")
+			return newConflictError("container_not_placed", "container is not currently placed")
+		}
+		if !slices.Contains(locks, container.BlockID) {
+			return newConflictError(CodeContainerMoved, "container moved while being picked up; retry the pickup")
+		}
+
+		block, err := tx.Blocks().FindByID(container.BlockID)
+		if err != nil {
+			return err
+		}
+		container.Block = *block
+
+		blockers, err := blockersOf(tx, *container)
+		if err != nil {
+			return err
+		}
+
+		response = &dto.PickupResponse{Message: "Success"}
+		if len(blockers) > 0 {
+			if !req.PlanRehandles && !req.ExecuteRehandles {
+				log.Printf("❌ Pickup blocked: %s has %d container(s) on top", req.ContainerNumber, len(blockers))
+				return blockedPickupError(*container, blockers)
+			}
+
+			rehandles, err := s.planRehandles(tx, *container, blockers)
+			if err != nil {
+				return err
+			}
+			response.Rehandles = rehandleMoves(container.Block, rehandles)
+
+			if !req.ExecuteRehandles {
+				log.Printf("📋 Planned %d rehandle(s) to pick up %s", len(rehandles), req.ContainerNumber)
+				response.Message = "Rehandles required"
+				return nil
+			}
+			if err := executeRehandles(tx, rehandles, req.User, &changes); err != nil {
+				return err
+			}
 		}
 
 		now := time.Now()
 		container.IsPlaced = false
 		container.PickedUpAt = &now
 
-		if err := tx.Save(&container).Error; err != nil {
+		if err := tx.Containers().Save(container); err != nil {
 			log.Printf("Failed to pickup container: %v", err)
 			return err
 		}
 
-		log.Printf("Container picked up successfully: %s", req.ContainerNumber)
+		fp := containerFootprint(*container)
+		if err := recordEvent(tx, containerEvent(EventPickedUp, container.ContainerNumber, container.Block.YardID,
+			at(container.Block.Name, fp), nil, req.User, "")); err != nil {
+			return err
+		}
+
+		log.Printf("Container picked up successfully: %s (freed Slot=%d-%d, Row=%d, Tier=%d)",
+			req.ContainerNumber, fp.StartSlot, fp.EndSlot(), fp.Row, fp.Tier)
+		response.PickedUp = true
+		changes.removed(container.Block, *container, fp)
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.applyOccupancy(changes)
+	return response, nil
+}
+
+type containerAttributes struct {
+	Size        int
+	Height      float64
+	Type        string
+	SizeType    string
+	OutOfGauge  bool
+	GrossWeight float64
+
+	ExpectedDeparture *time.Time
+	VesselVoyage      string
+}
+
+// resolveContainerAttributes takes the attributes from the placement request,
+// filling any that are missing from the container's latest suggestion.
+func resolveContainerAttributes(tx repositories.Store, req dto.PlacementRequest, yardID uint) (containerAttributes, error) {
+	attrs := containerAttributes{
+		Size:        req.ContainerSize,
+		Height:      req.ContainerHeight,
+		Type:        req.ContainerType,
+		SizeType:    req.SizeType,
+		OutOfGauge:  req.OutOfGauge,
+		GrossWeight: req.GrossWeight,
+
+		ExpectedDeparture: req.ExpectedDeparture,
+		VesselVoyage:      req.VesselVoyage,
+	}
+	if attrs.Size != 0 && attrs.Height != 0 && attrs.Type != "" {
+		return attrs, nil
+	}
+
+	suggestion, err := tx.Suggestions().FindByContainer(req.ContainerNumber)
+	if err != nil || suggestion.YardID != yardID {
+		return attrs, newInvalidError("attributes_required",
+			"container_size, container_height and container_type are required when the container has no prior suggestion")
+	}
+
+	log.Printf("ℹ️ Using suggested attributes for %s: Size=%d, Height=%.1f, Type=%s",
+		req.ContainerNumber, suggestion.ContainerSize, suggestion.ContainerHeight, suggestion.ContainerType)
+
+	if attrs.Size == 0 {
+		attrs.Size = suggestion.ContainerSize
+	}
+	if attrs.Height == 0 {
+		attrs.Height = suggestion.ContainerHeight
+	}
+	if attrs.Type == "" {
+		attrs.Type = suggestion.ContainerType
+	}
+	if attrs.SizeType == "" && attrs.Size == suggestion.ContainerSize &&
+		attrs.Height == suggestion.ContainerHeight && attrs.Type == suggestion.ContainerType {
+		attrs.SizeType = suggestion.SizeType
+	}
+	attrs.OutOfGauge = attrs.OutOfGauge || suggestion.OutOfGauge
+	if attrs.GrossWeight == 0 {
+		attrs.GrossWeight = suggestion.GrossWeight
+	}
+	if attrs.ExpectedDeparture == nil {
+		attrs.ExpectedDeparture = suggestion.ExpectedDeparture
+	}
+	if attrs.VesselVoyage == "" {
+		attrs.VesselVoyage = suggestion.VesselVoyage
+	}
+	return attrs, nil
+}
+
+// checkPlanMatch verifies that every cell of f in block belongs to a yard plan
+// for containers with the given attributes. Where plans overlap the highest
+// priority plan owns the cell.
+func checkPlanMatch(tx repositories.Store, block models.Block, f footprint, attrs containerAttributes) error {
+	plans, err := tx.Plans().ListByBlock(block.ID)
+	if err != nil {
+		return err
+	}
+
+	for slot := f.StartSlot; slot <= f.EndSlot(); slot++ {
+		plan := owningPlan(plans, slot, f.Row)
+		if plan == nil {
+			return newInvalidError("plan_mismatch",
+				fmt.Sprintf("position slot %d, row %d in block %s is not covered by any yard plan", slot, f.Row, block.Name))
+		}
+
+		if plan.ContainerSize != attrs.Size ||
+			plan.ContainerType != attrs.Type ||
+			math.Abs(plan.ContainerHeight-attrs.Height) >= 0.01 {
+			return newInvalidError("plan_mismatch",
+				fmt.Sprintf("position belongs to yard plan %d for %dft %.1f %s containers, not %dft %.1f %s",
+					plan.ID, plan.ContainerSize, plan.ContainerHeight, plan.ContainerType,
+					attrs.Size, attrs.Height, attrs.Type))
+		}
+	}
+
+	return nil
 }
 
 type position struct {
@@ -220,73 +727,164 @@ type position struct {
 	Tier int
 }
 
-func (s *YardService) findAvailablePosition(plan models.YardPlan, containerSize int) (*position, error) {
-	var occupiedPositions []struct {
-		Slot int
-		Row  int
-		Tier int
-	}
-
-	s.db.Model(&models.Container{}).Where(
-		"block_id = ? AND is_placed = ? AND slot BETWEEN ? AND ? AND row BETWEEN ? AND ?",
-		plan.BlockID, true, plan.StartSlot, plan.EndSlot, plan.StartRow, plan.EndRow,
-	).Select("slot, row, tier").Find(&occupiedPositions)
-
-	occupiedMap := make(map[string]bool)
-	for _, pos := range occupiedPositions {
-		key := getPositionKey(pos.Slot, pos.Row, pos.Tier)
-		occupiedMap[key] = true
-	}
-
-	switch plan.PriorityDirection {
-	case "LEFT_TO_RIGHT":
-		for tier := 1; tier <= plan.Block.MaxTier; tier++ {
-			for row := plan.StartRow; row <= plan.EndRow; row++ {
-				for slot := plan.StartSlot; slot <= plan.EndSlot; slot++ {
-					if containerSize == 40 {
-						if slot+1 > plan.EndSlot {
-							continue
-						}
-						if !isPositionOccupied(occupiedMap, slot, row, tier) &&
-							!isPositionOccupied(occupiedMap, slot+1, row, tier) {
-							return &position{Slot: slot, Row: row, Tier: tier}, nil
-						}
-					} else {
-						if !isPositionOccupied(occupiedMap, slot, row, tier) {
-							return &position{Slot: slot, Row: row, Tier: tier}, nil
-						}
-					}
-				}
+// rankedPosition is a valid position with the cost the engine ranked it by and
+// the reasons behind it.
+type rankedPosition struct {
+	position
+	plan    models.YardPlan
+	cost    float64
+	reasons []string
+}
+
+// score maps the ranking cost onto (0, 1], higher being better.
+func (r rankedPosition) score() float64 {
+	return math.Round(100/(1+r.cost)) / 100
+}
+
+// rankPositions scans the plan's occupancy once and returns up to limit valid
+// positions, best first. Cells covered by a shadowing (higher priority) plan
+// and positions reserved for other containers are skipped. Scored strategies
+// order positions by cost; other strategies by their candidate order. Plans
+// that stack by weight rank positions whose supporting containers are lighter
+// behind otherwise equal ones.
+func (s *YardService) rankPositions(tx repositories.Store, plan models.YardPlan, attrs containerAttributes, shadows []models.YardPlan, reserved []footprint, scale weightScale, limit int) ([]rankedPosition, error) {
+	occupiedMap, placed, err := s.blockOccupancy(tx, plan.Block, rankableFromCache(plan, attrs))
+	if err != nil {
+		return nil, err
+	}
+
+	stacks := blockStacks{occupied: occupiedMap, containers: placed, maxTier: plan.Block.MaxTier}
+	for _, f := range reserved {
+		occupiedMap.mark(f)
+	}
+
+	var plugs []models.ReeferPlug
+	if attrs.Type == ContainerTypeReefer {
+		plugs, _ = loadReeferPlugs(tx, plan.BlockID)
+	}
+
+	supportersOf := func(f footprint) []models.Container {
+		var supporters []models.Container
+		for _, container := range placed {
+			below := containerFootprint(container)
+			if below.Row == f.Row && below.Tier == f.Tier-1 &&
+				below.StartSlot <= f.EndSlot() && below.EndSlot() >= f.StartSlot {
+				supporters = append(supporters, container)
+			}
+		}
+		return supporters
+	}
+
+	fits := func(f footprint) bool {
+		if f.EndSlot() > plan.EndSlot {
+			return false
+		}
+		for cell := f.StartSlot; cell <= f.EndSlot(); cell++ {
+			if isShadowed(shadows, cell, f.Row) {
+				return false
 			}
 		}
-	case "BOTTOM_TO_TOP":
-		for slot := plan.StartSlot; slot <= plan.EndSlot; slot++ {
-			for row := plan.StartRow; row <= plan.EndRow; row++ {
-				for tier := 1; tier <= plan.Block.MaxTier; tier++ {
-					if containerSize == 40 {
-						if slot+1 > plan.EndSlot {
-							continue
-						}
-						if !isPositionOccupied(occupiedMap, slot, row, tier) &&
-							!isPositionOccupied(occupiedMap, slot+1, row, tier) {
-							return &position{Slot: slot, Row: row, Tier: tier}, nil
-						}
-					} else {
-						if !isPositionOccupied(occupiedMap, slot, row, tier) {
-							return &position{Slot: slot, Row: row, Tier: tier}, nil
-						}
-					}
-				}
+		if !occupiedMap.isFree(f) {
+			return false
+		}
+		if checkReeferPower(f, attrs.Type, plugs, plan.Block.Name) != nil {
+			return false
+		}
+		if attrs.closesStack() && occupiedMap.hasAbove(f, plan.Block.MaxTier) {
+			return false
+		}
+		if checkStackSupport(f, attrs.Size, supportersOf(f), plan.Block.AllowFortyOnTwenties) != nil {
+			return false
+		}
+		return checkStackHeight(f, attrs.Height, placed, plan.Block) == nil
+	}
+
+	preferHeavyBelow := plan.StackByWeight && attrs.GrossWeight > 0
+	heavierBelow := func(f footprint) bool {
+		if !preferHeavyBelow {
+			return true
+		}
+		for _, below := range supportersOf(f) {
+			if !scale.atLeastAsHeavy(below.GrossWeight, attrs.GrossWeight) {
+				return false
 			}
 		}
+		return true
 	}
 
-	return nil, errors.New("no available position found in the planned area")
-}
+	strategy, ok := LookupPlacementStrategy(plan.PriorityDirection)
+	if !ok {
+		return nil, fmt.Errorf("yard plan %d uses unknown placement strategy %s", plan.ID, plan.PriorityDirection)
+	}
+	scored, isScored := strategy.(ScoredPlacementStrategy)
+
+	area := PlanArea{
+		StartSlot: plan.StartSlot,
+		EndSlot:   plan.EndSlot,
+		StartRow:  plan.StartRow,
+		EndRow:    plan.EndRow,
+		MaxTier:   plan.Block.MaxTier,
+	}
+	incoming := IncomingContainer{
+		Size:              attrs.Size,
+		SlotSpan:          slotSpanForSize(attrs.Size),
+		GrossWeight:       attrs.GrossWeight,
+		ExpectedDeparture: attrs.ExpectedDeparture,
+		VesselVoyage:      attrs.VesselVoyage,
+	}
+
+	var ranked []rankedPosition
+	unpenalized := 0
+	for i, candidate := range strategy.Candidates(area, stacks) {
+		f := newFootprint(candidate.Slot, candidate.Row, candidate.Tier, attrs.Size)
+		if !fits(f) {
+			continue
+		}
+
+		reasons := []string{
+			fmt.Sprintf("matches plan %d (%s slots %d-%d, rows %d-%d)",
+				plan.ID, plan.Block.Name, plan.StartSlot, plan.EndSlot, plan.StartRow, plan.EndRow),
+			fmt.Sprintf("candidate %d in %s order", i+1, strategy.Name()),
+			fmt.Sprintf("tier %d of %d", candidate.Tier, plan.Block.MaxTier),
+		}
+
+		cost := 0.0
+		if isScored {
+			cost = scored.Cost(candidate, stacks, incoming)
+		}
+		if attrs.ExpectedDeparture == nil {
+			reasons = append(reasons, "rehandle cost unknown: no expected departure given")
+		} else {
+			reasons = append(reasons, fmt.Sprintf("rehandle cost %d", countRehandles(candidate, stacks, incoming)))
+		}
+		if !heavierBelow(f) {
+			// Weight order only breaks ties between equally costly positions.
+			cost += 0.5
+			reasons = append(reasons, "lighter container below")
+		}
+
+		ranked = append(ranked, rankedPosition{position: position{Slot: candidate.Slot, Row: candidate.Row, Tier: candidate.Tier},
+			plan: plan, cost: cost, reasons: reasons})
 
-func isPositionOccupied(occupiedMap map[string]bool, slot, row, tier int) bool {
-	key := getPositionKey(slot, row, tier)
-	return occupiedMap[key]
+		// Candidates arrive in strategy order, so without costs nothing later
+		// can outrank limit positions that carry no penalty.
+		if cost == 0 {
+			unpenalized++
+		}
+		if !isScored && unpenalized >= limit {
+			break
+		}
+	}
+
+	if len(ranked) == 0 {
+		return nil, errNoFreePosition
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].cost < ranked[j].cost })
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked, nil
 }
 
 func getPositionKey(slot, row, tier int) string {
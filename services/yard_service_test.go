@@ -0,0 +1,359 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"backend_yard_planning_system/dto"
+	"backend_yard_planning_system/repositories"
+)
+
+func TestSuggestionReservesPosition(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+
+		first, err := s.GetSuggestion(suggestionRequest("CONT0001"), 1)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		wantPosition(t, first.SuggestedPosition, 1, 1, 1)
+		if first.ReservedUntil == nil {
+			t.Fatal("suggestion reserves no position")
+		}
+
+		// The first position stays reserved for CONT0001 only.
+		second, err := s.GetSuggestion(suggestionRequest("CONT0002"), 1)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		wantPosition(t, second.SuggestedPosition, 2, 1, 1)
+
+		again, err := s.GetSuggestion(suggestionRequest("CONT0001"), 1)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		wantPosition(t, again.SuggestedPosition, 1, 1, 1)
+
+		wantCode(t, s.PlaceContainer(placementRequest("CONT0003", 1, 1, 1)), CodePositionReserved)
+	})
+}
+
+func TestSuggestionRanksPositions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+		place(t, s, "CONT0001", 1, 1, 1)
+
+		response, err := s.GetSuggestion(suggestionRequest("CONT0002"), 3)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		wantPosition(t, response.SuggestedPosition, 2, 1, 1)
+		if len(response.RankedPositions) != 3 {
+			t.Fatalf("ranked %d positions, want 3", len(response.RankedPositions))
+		}
+		for i, want := range [][3]int{{2, 1, 1}, {3, 1, 1}, {1, 2, 1}} {
+			wantPosition(t, response.RankedPositions[i].Position, want[0], want[1], want[2])
+		}
+	})
+}
+
+// TestSuggestionFollowsTopToBottom checks that TOP_TO_BOTTOM starts at the
+// plan's highest row and tops up a stack before the next one.
+func TestSuggestionFollowsTopToBottom(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+		request := planRequest()
+		request.PriorityDirection = "TOP_TO_BOTTOM"
+		if _, err := NewYardManagementService(store, NewRedisService(nil)).UpdatePlan("YRD1", testPlan(t, store).ID, request); err != nil {
+			t.Fatalf("UpdatePlan: %v", err)
+		}
+		place(t, s, "CONT0001", 1, 5, 1)
+
+		response, err := s.GetSuggestion(suggestionRequest("CONT0002"), 4)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		want := [][3]int{{1, 5, 2}, {2, 5, 1}, {3, 5, 1}, {1, 4, 1}}
+		if len(response.RankedPositions) != len(want) {
+			t.Fatalf("ranked %d positions, want %d", len(response.RankedPositions), len(want))
+		}
+		for i, position := range want {
+			wantPosition(t, response.RankedPositions[i].Position, position[0], position[1], position[2])
+		}
+	})
+}
+
+func TestSuggestionErrors(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+		place(t, s, "CONT0001", 1, 1, 1)
+
+		unknownYard := suggestionRequest("CONT0002")
+		unknownYard.Yard = "NOPE"
+		noPlan := suggestionRequest("CONT0002")
+		noPlan.ContainerType = "TANK"
+		tests := []struct {
+			name     string
+			request  dto.SuggestionRequest
+			wantKind ErrorKind
+			wantCode string
+		}{
+			{"unknown yard", unknownYard, KindNotFound, "yard_not_found"},
+			{"no matching plan", noPlan, KindRuleViolation, CodeNoMatchingPlan},
+			{"container already placed", suggestionRequest("CONT0001"), KindConflict, CodeContainerAlreadyPlaced},
+		}
+		for _, tt := range tests {
+			_, err := s.GetSuggestion(tt.request, 1)
+			if errorKind(err) != tt.wantKind || errorCode(err) != tt.wantCode {
+				t.Fatalf("%s: error = %v, want kind %v code %q", tt.name, err, tt.wantKind, tt.wantCode)
+			}
+		}
+
+		// Fill the plan's ground, then cap the block at one tier.
+		for slot := 1; slot <= 3; slot++ {
+			for row := 1; row <= 5; row++ {
+				if slot != 1 || row != 1 {
+					place(t, s, fmt.Sprintf("FILL%02d%02d", slot, row), slot, row, 1)
+				}
+			}
+		}
+		updateTestBlock(t, store, func(r *dto.BlockRequest) { r.MaxTier = 1 })
+		_, err := s.GetSuggestion(suggestionRequest("CONT0002"), 1)
+		wantCode(t, err, CodeNoAvailablePosition)
+		if errorKind(err) != KindRuleViolation {
+			t.Fatalf("error = %v, want a rule violation", err)
+		}
+	})
+}
+
+func TestPlacementTakesSuggestedAttributes(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+
+		suggested, err := s.GetSuggestion(suggestionRequest("CONT0001"), 1)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		p := suggested.SuggestedPosition
+		if err := s.PlaceContainer(dto.PlacementRequest{
+			Yard: "YRD1", ContainerNumber: "CONT0001", Block: p.Block, Slot: p.Slot, Row: p.Row, Tier: p.Tier,
+		}); err != nil {
+			t.Fatalf("PlaceContainer: %v", err)
+		}
+
+		container, err := store.Containers().FindByNumber("CONT0001")
+		if err != nil {
+			t.Fatalf("FindByNumber: %v", err)
+		}
+		if !container.IsPlaced || container.ContainerSize != 20 || container.ContainerType != "DRY" {
+			t.Fatalf("placed container = %+v, want a placed 20ft DRY", container)
+		}
+		if _, err := store.Suggestions().FindByContainer("CONT0001"); err != repositories.ErrNotFound {
+			t.Fatalf("suggestion after placement: %v, want it consumed", err)
+		}
+
+		next, err := s.GetSuggestion(suggestionRequest("CONT0002"), 1)
+		if err != nil {
+			t.Fatalf("GetSuggestion: %v", err)
+		}
+		wantPosition(t, next.SuggestedPosition, 2, 1, 1)
+	})
+}
+
+func TestPlacementRules(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+		place(t, s, "CONT0001", 1, 1, 1)
+
+		wantCode(t, s.PlaceContainer(placementRequest("CONT0002", 1, 1, 1)), CodePositionOccupied)
+		wantCode(t, s.PlaceContainer(placementRequest("CONT0002", 2, 1, 2)), CodeUnsupportedStack)
+		wantCode(t, s.PlaceContainer(placementRequest("CONT0001", 2, 1, 1)), CodeContainerAlreadyPlaced)
+		if err := s.PlaceContainer(placementRequest("CONT0002", 1, 1, 6)); err == nil {
+			t.Fatal("placement above the block's tiers succeeded")
+		}
+		place(t, s, "CONT0002", 1, 1, 2)
+	})
+}
+
+// TestConcurrentPlacementsOnOnePosition hammers one cell. Both stores run the
+// placements one after the other under the block lock, so only the occupancy
+// check turns the losers away; the unique index is covered by
+// TestPlacementLosingToTheUniqueIndex.
+func TestConcurrentPlacementsOnOnePosition(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+
+		const placements = 20
+		errs := make([]error, placements)
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := range placements {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				errs[i] = s.PlaceContainer(placementRequest(fmt.Sprintf("CONC%04d", i), 2, 3, 1))
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		placed := 0
+		for i, err := range errs {
+			if err == nil {
+				placed++
+				continue
+			}
+			if code := errorCode(err); code != CodePositionOccupied {
+				t.Errorf("placement %d failed with %v (code %q), want %q", i, err, code, CodePositionOccupied)
+			}
+		}
+		if placed != 1 {
+			t.Fatalf("%d placements succeeded, want exactly 1", placed)
+		}
+
+		block, err := store.Blocks().FindByName("YRD1", "LC01")
+		if err != nil {
+			t.Fatalf("FindByName: %v", err)
+		}
+		row, err := store.Containers().ListPlacedInRow(block.ID, 3)
+		if err != nil {
+			t.Fatalf("ListPlacedInRow: %v", err)
+		}
+		if len(row) != 1 {
+			t.Fatalf("%d containers placed in row 3, want 1", len(row))
+		}
+	})
+}
+
+func TestPlacementLosingToTheUniqueIndex(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		newTestYard(t, store, NewRedisService(nil))
+		block, err := store.Blocks().FindByName("YRD1", "LC01")
+		if err != nil {
+			t.Fatalf("FindByName: %v", err)
+		}
+
+		// A rival container lands between the occupancy check and the insert,
+		// as a placement that skipped the block lock would.
+		hooked := newHookedStore(store)
+		hooked.hooks.beforeCreate = func(tx repositories.Store) {
+			hooked.hooks.beforeCreate = nil
+			rival := box("RIVAL001", 20, 2, 3, 1)
+			rival.BlockID = block.ID
+			if err := tx.Containers().Create(&rival); err != nil {
+				t.Fatalf("Create rival: %v", err)
+			}
+		}
+		s := NewYardService(hooked, NewRedisService(nil))
+
+		err = s.PlaceContainer(placementRequest("CONT0001", 2, 3, 1))
+		wantCode(t, err, CodePositionOccupied)
+		if kind := errorKind(err); kind != KindConflict {
+			t.Fatalf("error kind = %v, want KindConflict (409)", kind)
+		}
+	})
+}
+
+func TestPlacementWriteError(t *testing.T) {
+	duplicate := fmt.Errorf("%w: UNIQUE constraint failed: containers.block_id", repositories.ErrDuplicate)
+	err := placementWriteError(duplicate)
+	wantCode(t, err, CodePositionOccupied)
+	if kind := errorKind(err); kind != KindConflict {
+		t.Fatalf("error kind = %v, want KindConflict (409)", kind)
+	}
+
+	other := errors.New("disk I/O error")
+	if err := placementWriteError(other); err != other {
+		t.Fatalf("placementWriteError(%v) = %v, want it unchanged", other, err)
+	}
+}
+
+func TestPickup(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+		place(t, s, "CONT0001", 1, 1, 1)
+
+		response, err := s.PickupContainer(dto.PickupRequest{Yard: "YRD1", ContainerNumber: "CONT0001"})
+		if err != nil {
+			t.Fatalf("PickupContainer: %v", err)
+		}
+		if !response.PickedUp {
+			t.Fatalf("pickup response = %+v, want picked up", response)
+		}
+
+		container, err := store.Containers().FindByNumber("CONT0001")
+		if err != nil {
+			t.Fatalf("FindByNumber: %v", err)
+		}
+		if container.IsPlaced || container.PickedUpAt == nil {
+			t.Fatalf("container after pickup = %+v, want it picked up", container)
+		}
+		if _, err := s.PickupContainer(dto.PickupRequest{Yard: "YRD1", ContainerNumber: "CONT0001"}); err == nil {
+			t.Fatal("second pickup succeeded")
+		}
+
+		// The freed position can be used again, also by the same container.
+		place(t, s, "CONT0001", 1, 1, 1)
+	})
+}
+
+func TestPickupWithRehandles(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store repositories.Store) {
+		s := newTestYard(t, store, NewRedisService(nil))
+		place(t, s, "CONT0001", 1, 1, 1)
+		place(t, s, "CONT0002", 1, 1, 2)
+		place(t, s, "CONT0003", 1, 1, 3)
+		pickup := dto.PickupRequest{Yard: "YRD1", ContainerNumber: "CONT0001"}
+
+		_, err := s.PickupContainer(pickup)
+		wantCode(t, err, CodeContainersAbove)
+
+		pickup.PlanRehandles = true
+		planned, err := s.PickupContainer(pickup)
+		if err != nil {
+			t.Fatalf("PickupContainer planning rehandles: %v", err)
+		}
+		if planned.PickedUp || len(planned.Rehandles) != 2 {
+			t.Fatalf("planned pickup = %+v, want 2 rehandles and no pickup", planned)
+		}
+		// Topmost first, and never back onto the stack being dug out.
+		if planned.Rehandles[0].ContainerNumber != "CONT0003" || planned.Rehandles[1].ContainerNumber != "CONT0002" {
+			t.Fatalf("rehandle order = %+v, want CONT0003 then CONT0002", planned.Rehandles)
+		}
+		wantPosition(t, planned.Rehandles[0].To, 2, 1, 1)
+		wantPosition(t, planned.Rehandles[1].To, 3, 1, 1)
+		if container, _ := store.Containers().FindByNumber("CONT0003"); container.Slot != 1 || container.Tier != 3 {
+			t.Fatalf("planning moved CONT0003 to %d/%d/%d", container.Slot, container.Row, container.Tier)
+		}
+
+		pickup.ExecuteRehandles = true
+		executed, err := s.PickupContainer(pickup)
+		if err != nil {
+			t.Fatalf("PickupContainer executing rehandles: %v", err)
+		}
+		if !executed.PickedUp || len(executed.Rehandles) != 2 {
+			t.Fatalf("executed pickup = %+v, want 2 rehandles and a pickup", executed)
+		}
+		for _, move := range executed.Rehandles {
+			container, err := store.Containers().FindByNumber(move.ContainerNumber)
+			if err != nil {
+				t.Fatalf("FindByNumber(%s): %v", move.ContainerNumber, err)
+			}
+			if !container.IsPlaced || container.Slot != move.To.Slot || container.Row != move.To.Row || container.Tier != move.To.Tier {
+				t.Fatalf("%s is at %d/%d/%d, want %+v", move.ContainerNumber, container.Slot, container.Row, container.Tier, move.To)
+			}
+
+			history, err := s.GetContainerHistory(move.ContainerNumber)
+			if err != nil {
+				t.Fatalf("GetContainerHistory: %v", err)
+			}
+			last := history.Events[len(history.Events)-1]
+			if last.Type != EventMoved || last.Reason != MoveReasonRehandle || last.From == nil {
+				t.Fatalf("last event of %s = %+v, want a rehandle move", move.ContainerNumber, last)
+			}
+		}
+	})
+}